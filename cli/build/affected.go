@@ -0,0 +1,89 @@
+// Author: lipixun
+// Created Time : 三 12/28 18:10:26 2016
+//
+// File Name: affected.go
+// Description:
+//	Find (and build) the targets affected by the changed files
+package build
+
+import (
+	"fmt"
+	opcli "github.com/ops-openlight/openlight/cli"
+	"github.com/ops-openlight/openlight/pkg/log"
+	"github.com/ops-openlight/openlight/pkg/sourcecode/repoloader"
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"github.com/ops-openlight/openlight/pkg/uri"
+	"github.com/ops-openlight/openlight/pkg/util"
+	"gopkg.in/urfave/cli.v1"
+	"path/filepath"
+)
+
+func Affected(c *cli.Context) error {
+	ws, err := opcli.GetWorkspace(c)
+	if err != nil {
+		return err
+	}
+	logger := ws.Logger.GetLoggerWithHeader(LogHeader)
+	// Get options
+	since := c.String("since")
+	if since == "" {
+		logger.LeveledPrintf(log.LevelError, "Require since commit\n")
+		return cli.NewExitError("", 1)
+	}
+	g, repo, err := loadCurrentRepositoryGraph(c, ws, true, logger)
+	if err != nil {
+		return err
+	}
+	rootPath := repo.Local.Path
+	// Get the changed files
+	files, err := repoloader.GetGitChangedFiles(rootPath, since)
+	if err != nil {
+		logger.LeveledPrintf(log.LevelError, "Failed to get changed files since [%s], error: %s\n", since, err)
+		return cli.NewExitError("", 1)
+	}
+	for _, file := range files {
+		logger.LeveledPrintf(log.LevelDebug, "Changed file: %s\n", file)
+	}
+	// Get the affected targets, only the targets of current repository are output
+	var targetUris []*uri.TargetUri
+	for _, target := range g.GetAffectedTargets(repo, files) {
+		if target.Repository.Uri != repo.Uri {
+			logger.LeveledPrintf(log.LevelDebug, "Affected target [%s] of other repository is ignored\n", target.Key())
+			continue
+		}
+		if !c.Bool("build") {
+			fmt.Println(target.Name)
+		}
+		targetUris = append(targetUris, &uri.TargetUri{Name: target.Name, Repository: &uri.RepositoryUri{Uri: rootPath}})
+	}
+	if !c.Bool("build") {
+		// Done
+		return nil
+	}
+	if len(targetUris) == 0 {
+		logger.Println("No target affected")
+		return nil
+	}
+	// Build the affected targets
+	output, err := util.GetRealPath(c.String("output"))
+	if err != nil {
+		logger.LeveledPrintf(log.LevelError, "Failed to get output real path, error: %s\n", err)
+		return cli.NewExitError("", 1)
+	}
+	remoteOverwrites, err := getRemoteOverwrites(c.StringSlice("repository-remote-overwrite"), logger)
+	if err != nil {
+		logger.LeveledPrintf(log.LevelError, "Failed to load repository remote overwrites, error: %s\n", err)
+		return cli.NewExitError("", 1)
+	}
+	options := BuildOptions{
+		AllowLocal:       true,
+		OnlyLocal:        true,
+		Output:           output,
+		DisableFinder:    c.Bool("disable-finder"),
+		ResolutionPolicy: c.String("resolution-policy"),
+		ConflictPolicy:   c.String("conflict-policy"),
+		RemoteOverwrites: remoteOverwrites,
+		LockFile:         filepath.Join(rootPath, spec.LockFileName),
+	}
+	return build(targetUris, ws, options, logger)
+}
@@ -7,10 +7,12 @@
 package build
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	opcli "github.com/ops-openlight/openlight/cli"
 	"github.com/ops-openlight/openlight/pkg/log"
+	"github.com/ops-openlight/openlight/pkg/sourcecode"
 	"github.com/ops-openlight/openlight/pkg/sourcecode/builder"
 	"github.com/ops-openlight/openlight/pkg/sourcecode/graph"
 	"github.com/ops-openlight/openlight/pkg/sourcecode/repoloader"
@@ -22,12 +24,41 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"text/tabwriter"
 )
 
 const (
 	REPO_URI_OVERWRITE_ENV_PREFIX = "OP_SOURCECODE_REPO_"
 )
 
+var (
+	// The graph flags shared by local-build and lock
+	graphFlags = []cli.Flag{
+		cli.BoolFlag{
+			Name:  "disable-finder",
+			Usage: "Disable the repository local finder",
+		},
+		cli.StringFlag{
+			Name:  "resolution-policy",
+			Value: "prefer-local",
+			Usage: "How to resolve the referenced repository, either prefer-local, require-local or prefer-remote",
+		},
+		cli.StringFlag{
+			Name:  "conflict-policy",
+			Value: "fail",
+			Usage: "How to resolve the conflicting branch/commit requests of a repository, either fail, first-wins, root-wins or override (by the overrides of root repository spec)",
+		},
+		cli.BoolFlag{
+			Name:  "show-resolution",
+			Usage: "Show where every loaded repository comes from (overwrite, finder or remote)",
+		},
+		cli.StringSliceFlag{
+			Name:  "repository-remote-overwrite, w",
+			Usage: "Overwrite the repository remote (or local path). Format: uri:path",
+		},
+	}
+)
+
 // Local build command
 func LocalBuild(c *cli.Context) error {
 	ws, err := opcli.GetWorkspace(c)
@@ -47,6 +78,38 @@ func LocalBuild(c *cli.Context) error {
 	}
 	// Get options
 	disableFinder := c.Bool("disable-finder")
+	resolutionPolicy := c.String("resolution-policy")
+	if !graph.IsValidResolutionPolicy(resolutionPolicy) {
+		logger.LeveledPrintf(log.LevelError, "Unknown resolution policy: %s\n", resolutionPolicy)
+		return cli.NewExitError("", 1)
+	}
+	conflictPolicy := c.String("conflict-policy")
+	if !graph.IsValidConflictPolicy(conflictPolicy) {
+		logger.LeveledPrintf(log.LevelError, "Unknown conflict policy: %s\n", conflictPolicy)
+		return cli.NewExitError("", 1)
+	}
+	noPublish := c.Bool("no-publish")
+	tagStrategy := c.String("tag-strategy")
+	tagTemplate := c.String("tag-template")
+	if tagTemplate != "" && tagStrategy == builder.DefaultTagStrategy {
+		// Use template strategy if only template is specified
+		tagStrategy = builder.TagStrategyTemplate
+	}
+	var retention *builder.RetentionPolicy
+	if c.Bool("auto-clean") {
+		policy := getRetentionPolicy(c)
+		if policy.IsEmpty() {
+			// Never remove all other builds automatically
+			policy = builder.DefaultRetentionPolicy
+			logger.LeveledPrintf(log.LevelDebug, "No retention flag specified, auto clean by default policy: keep last %d and referenced builds\n", policy.KeepLast)
+		}
+		retention = &policy
+	}
+	outputMode := c.String("output-mode")
+	if !builder.IsValidOutputMode(outputMode) {
+		logger.LeveledPrintf(log.LevelError, "Unknown output mode: %s\n", outputMode)
+		return cli.NewExitError("", 1)
+	}
 	// Get repository uri overwrites
 	remoteOverwrites, err := getRemoteOverwrites(c.StringSlice("repository-remote-overwrite"), logger)
 	if err != nil {
@@ -67,7 +130,30 @@ func LocalBuild(c *cli.Context) error {
 		logger.LeveledPrintf(log.LevelError, "Failed to get output abs path, error: %s\n", err)
 		return cli.NewExitError("", 1)
 	}
+	// Get the lfs store path
+	var lfsStore string
+	if c.String("lfs-store") != "" {
+		lfsStore, err = filepath.Abs(c.String("lfs-store"))
+		if err != nil {
+			logger.LeveledPrintf(log.LevelError, "Failed to get lfs store abs path, error: %s\n", err)
+			return cli.NewExitError("", 1)
+		}
+	}
+	// Get the trace path
+	var trace string
+	if c.String("trace") != "" {
+		trace, err = filepath.Abs(c.String("trace"))
+		if err != nil {
+			logger.LeveledPrintf(log.LevelError, "Failed to get trace abs path, error: %s\n", err)
+			return cli.NewExitError("", 1)
+		}
+		if traceFormat := c.String("trace-format"); traceFormat != sourcecode.TraceFormatChrome && traceFormat != sourcecode.TraceFormatTimeline {
+			logger.LeveledPrintf(log.LevelError, "Unknown trace format [%s]\n", traceFormat)
+			return cli.NewExitError("", 1)
+		}
+	}
 	// Adjust the target uri, create workspace file system
+	var lockFile string
 	currentProjectRootPath, err := opcli.GetGitRootFromCurrentDirectory()
 	if err != nil {
 		// Failed to get git root, check the target uris
@@ -77,11 +163,13 @@ func LocalBuild(c *cli.Context) error {
 		}
 		for _, targetUri := range targetUris {
 			if targetUri.Repository != nil {
-				logger.LeveledPrintf(log.LevelError, "Failed to get current git root directory (and which is required by target %s), error: %s\n", targetUri.Name, err)
+				logger.LeveledPrintf(log.LevelError, "Failed to get current git root directory (and which is required by target %s), error: %s\n", targetUri.Target(), err)
 				return cli.NewExitError("", 1)
 			}
 		}
 	} else {
+		// Use the lock file of current repository
+		lockFile = filepath.Join(currentProjectRootPath, spec.LockFileName)
 		// Great, check the target uris
 		if len(targetUris) == 0 {
 			// No target uri defined, add current repository
@@ -104,8 +192,24 @@ func LocalBuild(c *cli.Context) error {
 		AllowLocal:       true,
 		OnlyLocal:        true,
 		Output:           output,
+		OutputMode:       outputMode,
+		NoPublish:        noPublish,
+		RefuseDirty:      c.Bool("refuse-dirty"),
+		Submodules:       c.Bool("submodules"),
+		LFS:              c.Bool("lfs"),
+		LFSStore:         lfsStore,
+		Trace:            trace,
+		TraceFormat:      c.String("trace-format"),
+		TagStrategy:      tagStrategy,
+		TagTemplate:      tagTemplate,
+		Retention:        retention,
 		DisableFinder:    disableFinder,
+		ResolutionPolicy: resolutionPolicy,
+		ConflictPolicy:   conflictPolicy,
+		ShowResolution:   c.Bool("show-resolution"),
 		RemoteOverwrites: remoteOverwrites,
+		LockFile:         lockFile,
+		UpdateLock:       c.Bool("update-lock"),
 	}
 	return build(targetUris, ws, options, logger)
 }
@@ -114,10 +218,20 @@ func Build(c *cli.Context) error {
 	return cli.NewExitError("Not implemented", 1)
 }
 
+// Check if the target is in the target list
+func containsTarget(targets []*spec.Target, target *spec.Target) bool {
+	for _, t := range targets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
 // Get the uri overwrites from flags and environments
 func getRemoteOverwrites(flags []string, logger log.Logger) (map[string]string, error) {
 	// Initialize the local path mapping by environment and add flags since we want to let flag overwrite the path from environment variables
-	var remoteOverwrites map[string]string
+	remoteOverwrites := make(map[string]string)
 	for _, env := range os.Environ() {
 		if strings.HasPrefix(env, REPO_URI_OVERWRITE_ENV_PREFIX) {
 			idx := strings.Index(env, "=")
@@ -148,7 +262,7 @@ func getRemoteOverwrites(flags []string, logger log.Logger) (map[string]string,
 		}
 		path, err := util.GetRealPath(flag[idx+1:])
 		if err != nil {
-			return nil, errors.New(fmt.Sprintf("Malformed repository remote overwrites argument [%s], error: %s", err))
+			return nil, errors.New(fmt.Sprintf("Malformed repository remote overwrites argument [%s], error: %s", flag, err))
 		}
 		remoteOverwrites[uri] = path
 	}
@@ -162,6 +276,40 @@ func showRemoteOverwrites(remoteOverwrites map[string]string, logger log.Logger)
 	}
 }
 
+func showResolutionReport(resolutions []*graph.RepositoryResolution, logger log.Logger) {
+	logger.Println("Repository resolution:")
+	for _, resolution := range resolutions {
+		logger.Printf("\t%s\n", resolution.String())
+	}
+}
+
+func showConflictReport(conflicts []*graph.RepositoryConflict, logger log.Logger) {
+	for _, conflict := range conflicts {
+		logger.LeveledPrintf(log.LevelWarn, "Repository [%s] has conflicting requests, resolved to [%s] by policy [%s]:\n", conflict.Uri, conflict.Resolved, conflict.Policy)
+		for _, request := range conflict.Requests {
+			logger.LeveledPrintf(log.LevelWarn, "\t%s\n", request.String())
+		}
+	}
+}
+
+func showBuildSummary(summary *spec.BuildSummary, logger log.Logger) {
+	var buffer bytes.Buffer
+	writer := tabwriter.NewWriter(&buffer, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "\tTarget\tPrepare\tBuild\tCopy\tCache\t")
+	for _, timing := range summary.Targets {
+		cache := "-"
+		if timing.Cached {
+			cache = "cached"
+		}
+		fmt.Fprintf(writer, "\t%s\t%.2fs\t%.2fs\t%.2fs\t%s\t\n", timing.Target, timing.PrepareTimeUsage, timing.BuildTimeUsage, timing.CopyTimeUsage, cache)
+	}
+	writer.Flush()
+	logger.Println("Build summary:")
+	logger.Printf("%s", buffer.String())
+	logger.Printf("Critical path (%.2fs): %s\n", summary.CriticalPathTimeUsage, strings.Join(summary.CriticalPath, " --> "))
+	logger.Printf("Total time: %.2fs\n", summary.TotalTimeUsage)
+}
+
 // Get the default target uri
 func getDefaultTargetUri(path string) (*uri.TargetUri, error) {
 	spec, err := repoloader.LoadRepositorySpecFromFile(filepath.Join(path, spec.SpecFileName))
@@ -179,14 +327,56 @@ type BuildOptions struct {
 	AllowLocal       bool
 	OnlyLocal        bool
 	Output           string
+	OutputMode       string
+	NoPublish        bool
+	RefuseDirty      bool   // Refuse to build the targets whose repository has uncommitted changes
+	Submodules       bool   // Init and update the git submodules of the target repositories recursively
+	LFS              bool   // Fetch the git LFS objects of the target repositories
+	LFSStore         string // The local git LFS store, empty means the default store of each repository
+	Trace            string // Write the loading and building spans to this file, empty means no trace
+	TraceFormat      string // The format of the trace file, chrome or timeline
+	TagStrategy      string
+	TagTemplate      string
+	Retention        *builder.RetentionPolicy // Clean the build data by this policy after a successful build, nil means no automatic cleanup
 	DisableFinder    bool
+	ResolutionPolicy string
+	ConflictPolicy   string
+	ShowResolution   bool // Show the resolution report after the graph is loaded
 	RemoteOverwrites map[string]string
+	LockFile         string // The lock file to honor, empty means no lock
+	UpdateLock       bool   // Ignore the lock and update it after the graph is loaded
 }
 
 // Start the build process
 func build(targetUris []*uri.TargetUri, ws *workspace.Workspace, options BuildOptions, logger log.Logger) error {
+	// Record the spans, the trace file is written even if failed to build
+	var recorder *sourcecode.TraceRecorder
+	if options.Trace != "" {
+		recorder = sourcecode.NewTraceRecorder()
+		defer func() {
+			if err := recorder.WriteFile(options.Trace, options.TraceFormat); err != nil {
+				logger.LeveledPrintf(log.LevelWarn, "Failed to write trace file [%s], error: %s\n", options.Trace, err)
+			} else {
+				logger.LeveledPrintf(log.LevelInfo, "Trace file written: %s\n", options.Trace)
+			}
+		}()
+	}
 	// Load the source code graph
-	g, err := graph.New(ws, graph.GraphOptions{UseLocalDependency: options.AllowLocal, DisableFinder: options.DisableFinder})
+	graphOptions := graph.GraphOptions{UseLocalDependency: options.AllowLocal, DisableFinder: options.DisableFinder, ResolutionPolicy: options.ResolutionPolicy, ConflictPolicy: options.ConflictPolicy, LFSStore: options.LFSStore, TraceRecorder: recorder}
+	var lock *spec.RepositoryLock
+	if options.LockFile != "" {
+		var err error
+		lock, err = repoloader.LoadRepositoryLockFromFile(options.LockFile)
+		if err != nil {
+			logger.LeveledPrintf(log.LevelError, "Failed to load lock file [%s], error: %s\n", options.LockFile, err)
+			return cli.NewExitError("", 1)
+		}
+		if lock != nil && !options.UpdateLock {
+			logger.LeveledPrintf(log.LevelDebug, "Use lock file: %s\n", options.LockFile)
+			graphOptions.Lock = lock
+		}
+	}
+	g, err := graph.New(ws, graphOptions)
 	if err != nil {
 		logger.LeveledPrintf(log.LevelError, "Failed to create sourcecode graph, error: %s\n", err)
 		return cli.NewExitError("", 1)
@@ -199,30 +389,74 @@ func build(targetUris []*uri.TargetUri, ws *workspace.Workspace, options BuildOp
 	// Load the repository with the targets
 	var targets []*spec.Target
 	for _, targetUri := range targetUris {
-		r, err := g.Load(targetUri.Repository.Uri, graph.LoadOptions{Branch: targetUri.Repository.Branch, Commit: targetUri.Repository.Commit, Targets: []string{targetUri.Name}})
+		r, err := g.Load(targetUri.Repository.Uri, graph.LoadOptions{Branch: targetUri.Repository.Branch, Commit: targetUri.Repository.Commit, Tag: targetUri.Repository.Tag, Subdir: targetUri.Repository.Subdir, Submodules: options.Submodules, LFS: options.LFS, Targets: []string{targetUri.Target()}})
 		if err != nil {
-			logger.LeveledPrintf(log.LevelError, "Failed to load target [%s] remote [%s], err: %s\n", targetUri.Name, targetUri.Repository.Uri, err)
+			logger.LeveledPrintf(log.LevelError, "Failed to load target [%s] remote [%s], err: %s\n", targetUri.Target(), targetUri.Repository.Uri, err)
 			return cli.NewExitError("", 1)
 		}
-		target := g.Targets[spec.GetTargetKey(targetUri.Name, r)]
-		if target == nil {
-			logger.LeveledPrintf(log.LevelError, "Target [%s] not loaded after repository loaded\n", targetUri.Name)
+		names := []string{targetUri.Name}
+		if targetUri.IsPattern() {
+			names = r.Spec.GetTargetNamesByUri(targetUri)
+			logger.LeveledPrintf(log.LevelDebug, "Target pattern [%s] expanded to: %s\n", targetUri.Target(), strings.Join(names, ", "))
+		}
+		for _, name := range names {
+			target := g.Targets[spec.GetTargetKey(name, r)]
+			if target == nil {
+				logger.LeveledPrintf(log.LevelError, "Target [%s] not loaded after repository loaded\n", name)
+				return cli.NewExitError("", 1)
+			}
+			if !containsTarget(targets, target) {
+				targets = append(targets, target)
+			}
+		}
+	}
+	if options.ShowResolution {
+		showResolutionReport(g.GetResolutionReport(), logger)
+	}
+	showConflictReport(g.GetConflictReport(), logger)
+	if options.UpdateLock && options.LockFile != "" {
+		// Update the entries of the loaded repositories, the others are kept
+		// The repositories resolved from local are not updated since they may have commits not pushed to the remote
+		if lock == nil {
+			lock = &spec.RepositoryLock{Repositories: make(map[string]*spec.RepositoryLockEntry)}
+		}
+		for uri, entry := range g.GenerateLock().Repositories {
+			if resolution, ok := g.Resolutions[uri]; ok && resolution.IsLocal() {
+				logger.LeveledPrintf(log.LevelWarn, "Lock of repository [%s] is not updated since it's resolved from [%s] by %s, use op lock to lock it explicitly\n", uri, resolution.Remote, resolution.Source)
+				continue
+			}
+			lock.Repositories[uri] = entry
+		}
+		if err := repoloader.SaveRepositoryLockToFile(lock, options.LockFile); err != nil {
+			logger.LeveledPrintf(log.LevelError, "Failed to save lock file [%s], error: %s\n", options.LockFile, err)
 			return cli.NewExitError("", 1)
 		}
-		targets = append(targets, target)
+		logger.LeveledPrintf(log.LevelInfo, "Lock file updated: %s\n", options.LockFile)
 	}
 	// Create the builder
-	buildTag, err := builder.NewTag()
-	if err != nil {
+	builderOptions := builder.NewBuilderOptions("", options.Output)
+	if options.OutputMode != "" {
+		builderOptions.OutputMode = options.OutputMode
+	}
+	if options.TagStrategy != "" {
+		builderOptions.TagStrategy = options.TagStrategy
+	}
+	builderOptions.TagTemplate = options.TagTemplate
+	// The build tag is derived from the repository of the first target
+	if err := builderOptions.GenerateTag(targets[0].Repository); err != nil {
 		logger.LeveledPrintf(log.LevelError, "Failed to generate build tag, error: %s\n", err)
 		return cli.NewExitError("", 1)
 	}
-	logger.LeveledPrintf(log.LevelWarn, "Build tag generated: %s\n", buildTag)
-	b, err := builder.New(g, builder.NewBuilderOptions(buildTag, options.Output))
+	builderOptions.NoPublish = options.NoPublish
+	builderOptions.RefuseDirty = options.RefuseDirty
+	builderOptions.TraceRecorder = recorder
+	b, err := builder.New(g, builderOptions)
 	if err != nil {
 		logger.LeveledPrintf(log.LevelError, "Failed to create builder, error: %s\n", err)
 		return cli.NewExitError("", 1)
 	}
+	buildTag := b.Options.Tag
+	logger.LeveledPrintf(log.LevelWarn, "Build tag generated: %s\n", buildTag)
 	// Build the targets
 	for _, target := range targets {
 		logger.Printf("Start build target %s\n", target.Key())
@@ -233,9 +467,28 @@ func build(targetUris []*uri.TargetUri, ws *workspace.Workspace, options BuildOp
 		}
 		for name, art := range buildResult.Artifacts {
 			logger.Printf("\tArtifact generated: %s --> %s\n", name, art.String())
+			for _, u := range buildResult.Published[name] {
+				logger.Printf("\tArtifact published: %s --> %s\n", name, u)
+			}
 		}
 	}
 	logger.Println("Build completed")
+	if summary, err := b.GetBuildSummary(targets...); err != nil {
+		logger.LeveledPrintf(log.LevelWarn, "Failed to get build summary, error: %s\n", err)
+	} else {
+		showBuildSummary(summary, logger)
+	}
+	// Clean the build data
+	if options.Retention != nil {
+		policy := *options.Retention
+		policy.KeepTags = append(policy.KeepTags, buildTag)
+		result, err := builder.CleanBuildDataByPolicy(ws, policy, false)
+		if err != nil {
+			logger.LeveledPrintf(log.LevelWarn, "Failed to clean build data, error: %s\n", err)
+		} else {
+			showCleanResult(result, false, logger)
+		}
+	}
 	// Done
 	return nil
 }
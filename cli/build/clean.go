@@ -7,23 +7,94 @@
 package build
 
 import (
+	"fmt"
 	opcli "github.com/ops-openlight/openlight/cli"
 	"github.com/ops-openlight/openlight/pkg/log"
 	"github.com/ops-openlight/openlight/pkg/sourcecode/builder"
 	"gopkg.in/urfave/cli.v1"
 )
 
+var (
+	// The retention flags shared by clean-build and local-build
+	retentionFlags = []cli.Flag{
+		cli.IntFlag{
+			Name:  "keep-last",
+			Usage: "Keep the last N builds",
+		},
+		cli.DurationFlag{
+			Name:  "keep-within",
+			Usage: "Keep the builds younger than this duration, e.g. 72h",
+		},
+		cli.BoolFlag{
+			Name:  "keep-referenced",
+			Usage: "Keep the builds referenced by the symbol links in their output path",
+		},
+	}
+)
+
 func Clean(c *cli.Context) error {
 	ws, err := opcli.GetWorkspace(c)
 	if err != nil {
 		return err
 	}
 	logger := ws.Logger.GetLoggerWithHeader(LogHeader)
-	// Run clean
-	if err := builder.CleanBuildData(ws); err != nil {
+	policy := getRetentionPolicy(c)
+	dryRun := c.Bool("dry-run")
+	if policy.IsEmpty() && !dryRun {
+		// Run clean
+		if err := builder.CleanBuildData(ws); err != nil {
+			logger.LeveledPrintf(log.LevelError, "Failed to clean build data, error: %s\n", err)
+			return cli.NewExitError("", 1)
+		}
+		// Done
+		return nil
+	}
+	// Run clean by retention policy
+	result, err := builder.CleanBuildDataByPolicy(ws, policy, dryRun)
+	if err != nil {
 		logger.LeveledPrintf(log.LevelError, "Failed to clean build data, error: %s\n", err)
 		return cli.NewExitError("", 1)
 	}
+	showCleanResult(result, dryRun, logger)
 	// Done
 	return nil
 }
+
+// Get the retention policy from flags
+func getRetentionPolicy(c *cli.Context) builder.RetentionPolicy {
+	return builder.RetentionPolicy{
+		KeepLast:       c.Int("keep-last"),
+		KeepWithin:     c.Duration("keep-within"),
+		KeepReferenced: c.Bool("keep-referenced"),
+	}
+}
+
+func showCleanResult(result *builder.CleanResult, dryRun bool, logger log.Logger) {
+	for _, build := range result.Removed {
+		if dryRun {
+			logger.Printf("Will remove build [%s] built at [%s] size [%s]\n", build.Info.Tag, build.Info.Time.Format("2006-01-02 15:04:05"), formatSize(build.Size))
+		} else {
+			logger.LeveledPrintf(log.LevelDebug, "Removed build [%s] built at [%s] size [%s]\n", build.Info.Tag, build.Info.Time.Format("2006-01-02 15:04:05"), formatSize(build.Size))
+		}
+	}
+	if dryRun {
+		logger.Printf("%d builds kept, %d builds to remove, %s reclaimable\n", len(result.Kept), len(result.Removed), formatSize(result.ReclaimedBytes))
+	} else {
+		logger.Printf("%d builds kept, %d builds removed, %s reclaimed\n", len(result.Kept), len(result.Removed), formatSize(result.ReclaimedBytes))
+	}
+}
+
+// Format the size in bytes to human readable string
+func formatSize(size int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	value := float64(size)
+	i := 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d%s", size, units[i])
+	}
+	return fmt.Sprintf("%.1f%s", value, units[i])
+}
@@ -0,0 +1,128 @@
+// Author: lipixun
+// Created Time : 四 12/29 11:02:39 2016
+//
+// File Name: graph.go
+// Description:
+//	The graph commands
+package build
+
+import (
+	"fmt"
+	opcli "github.com/ops-openlight/openlight/cli"
+	"github.com/ops-openlight/openlight/pkg/log"
+	"github.com/ops-openlight/openlight/pkg/sourcecode/graph"
+	"github.com/ops-openlight/openlight/pkg/sourcecode/repoloader"
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"github.com/ops-openlight/openlight/pkg/uri"
+	"github.com/ops-openlight/openlight/pkg/workspace"
+	"gopkg.in/urfave/cli.v1"
+	"path/filepath"
+)
+
+// Output the targets depending on a target
+// Only the targets in the graph of current repository (and the roots specified by --root) are searched
+func GraphRdeps(c *cli.Context) error {
+	ws, err := opcli.GetWorkspace(c)
+	if err != nil {
+		return err
+	}
+	logger := ws.Logger.GetLoggerWithHeader(LogHeader)
+	if len(c.Args()) != 1 {
+		logger.LeveledPrintf(log.LevelError, "Require exactly one target uri\n")
+		return cli.NewExitError("", 1)
+	}
+	targetUri := uri.ParseTargetUri(c.Args()[0])
+	if targetUri == nil {
+		logger.LeveledPrintf(log.LevelError, "Failed to parse target uri from arg: %s\n", c.Args()[0])
+		return cli.NewExitError("", 1)
+	}
+	g, repo, err := loadCurrentRepositoryGraph(c, ws, true, logger)
+	if err != nil {
+		return err
+	}
+	// Load the other root repositories, the dependents are only searched in the loaded repositories
+	for _, root := range c.StringSlice("root") {
+		if _, err := g.Load(root, graph.LoadOptions{}); err != nil {
+			logger.LeveledPrintf(log.LevelError, "Failed to load repository [%s], error: %s\n", root, err)
+			return cli.NewExitError("", 1)
+		}
+	}
+	// Get the target
+	repoUri := repo.Uri
+	if targetUri.Repository != nil {
+		repoUri = targetUri.Repository.Uri
+	}
+	target, ok := g.Targets[fmt.Sprintf("%s:%s", repoUri, targetUri.Name)]
+	if !ok {
+		logger.LeveledPrintf(log.LevelError, "Target [%s] not found in the graph\n", targetUri.String())
+		return cli.NewExitError("", 1)
+	}
+	// Get the dependents in topological order
+	dependents, err := g.TopologicalSort(g.GetDependents(target, !c.Bool("direct")))
+	if err != nil {
+		logger.LeveledPrintf(log.LevelError, "Failed to sort the dependents, error: %s\n", err)
+		return cli.NewExitError("", 1)
+	}
+	for _, dependent := range dependents {
+		fmt.Println(dependent.Key())
+	}
+	// Done
+	return nil
+}
+
+// Load the graph of current repository with all targets by the graph flags
+// Parameters:
+// 	useLock 		Load the repositories at the locked commits if the lock file exists
+func loadCurrentRepositoryGraph(c *cli.Context, ws *workspace.Workspace, useLock bool, logger log.Logger) (*graph.Graph, *spec.Repository, error) {
+	// Get options
+	resolutionPolicy := c.String("resolution-policy")
+	if !graph.IsValidResolutionPolicy(resolutionPolicy) {
+		logger.LeveledPrintf(log.LevelError, "Unknown resolution policy: %s\n", resolutionPolicy)
+		return nil, nil, cli.NewExitError("", 1)
+	}
+	conflictPolicy := c.String("conflict-policy")
+	if !graph.IsValidConflictPolicy(conflictPolicy) {
+		logger.LeveledPrintf(log.LevelError, "Unknown conflict policy: %s\n", conflictPolicy)
+		return nil, nil, cli.NewExitError("", 1)
+	}
+	remoteOverwrites, err := getRemoteOverwrites(c.StringSlice("repository-remote-overwrite"), logger)
+	if err != nil {
+		logger.LeveledPrintf(log.LevelError, "Failed to load repository remote overwrites, error: %s\n", err)
+		return nil, nil, cli.NewExitError("", 1)
+	}
+	rootPath, err := opcli.GetGitRootFromCurrentDirectory()
+	if err != nil {
+		logger.LeveledPrintf(log.LevelError, "Failed to get current git root directory, error: %s\n", err)
+		return nil, nil, cli.NewExitError("", 1)
+	}
+	graphOptions := graph.GraphOptions{UseLocalDependency: true, DisableFinder: c.Bool("disable-finder"), ResolutionPolicy: resolutionPolicy, ConflictPolicy: conflictPolicy}
+	if useLock {
+		lockFile := filepath.Join(rootPath, spec.LockFileName)
+		lock, err := repoloader.LoadRepositoryLockFromFile(lockFile)
+		if err != nil {
+			logger.LeveledPrintf(log.LevelError, "Failed to load lock file [%s], error: %s\n", lockFile, err)
+			return nil, nil, cli.NewExitError("", 1)
+		}
+		graphOptions.Lock = lock
+	}
+	// Load all targets of current repository
+	g, err := graph.New(ws, graphOptions)
+	if err != nil {
+		logger.LeveledPrintf(log.LevelError, "Failed to create sourcecode graph, error: %s\n", err)
+		return nil, nil, cli.NewExitError("", 1)
+	}
+	for uri, remote := range remoteOverwrites {
+		g.RemoteOverwrites[uri] = remote
+	}
+	repo, err := g.Load(rootPath, graph.LoadOptions{})
+	if err != nil {
+		logger.LeveledPrintf(log.LevelError, "Failed to load repository [%s], error: %s\n", rootPath, err)
+		return nil, nil, cli.NewExitError("", 1)
+	}
+	if c.Bool("show-resolution") {
+		showResolutionReport(g.GetResolutionReport(), logger)
+	}
+	showConflictReport(g.GetConflictReport(), logger)
+	// Done
+	return g, repo, nil
+}
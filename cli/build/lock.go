@@ -0,0 +1,69 @@
+// Author: lipixun
+// Created Time : 二 12/27 15:02:37 2016
+//
+// File Name: lock.go
+// Description:
+//	Lock the repository references
+package build
+
+import (
+	opcli "github.com/ops-openlight/openlight/cli"
+	"github.com/ops-openlight/openlight/pkg/log"
+	"github.com/ops-openlight/openlight/pkg/sourcecode/repoloader"
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"gopkg.in/urfave/cli.v1"
+	"path/filepath"
+)
+
+func Lock(c *cli.Context) error {
+	ws, err := opcli.GetWorkspace(c)
+	if err != nil {
+		return err
+	}
+	logger := ws.Logger.GetLoggerWithHeader(LogHeader)
+	g, repo, err := loadCurrentRepositoryGraph(c, ws, false, logger)
+	if err != nil {
+		return err
+	}
+	lockFile := filepath.Join(repo.Local.Path, spec.LockFileName)
+	if c.Bool("check") {
+		// Check the lock
+		lock, err := repoloader.LoadRepositoryLockFromFile(lockFile)
+		if err != nil {
+			logger.LeveledPrintf(log.LevelError, "Failed to load lock file [%s], error: %s\n", lockFile, err)
+			return cli.NewExitError("", 1)
+		}
+		if lock == nil {
+			logger.LeveledPrintf(log.LevelError, "Lock file [%s] not found\n", lockFile)
+			return cli.NewExitError("", 1)
+		}
+		problems := g.CheckLock(lock)
+		if len(problems) > 0 {
+			for _, problem := range problems {
+				logger.LeveledPrintf(log.LevelError, "%s\n", problem)
+			}
+			logger.LeveledPrintf(log.LevelError, "Lock file [%s] is stale, run op lock to update it\n", lockFile)
+			return cli.NewExitError("", 1)
+		}
+		logger.Println("Lock file is up to date")
+		// Done
+		return nil
+	}
+	// Write the lock, the resolution source is recorded in the lock entry
+	for _, resolution := range g.GetResolutionReport() {
+		if resolution.IsLocal() {
+			logger.LeveledPrintf(log.LevelWarn, "Repository [%s] is resolved from [%s] by %s instead of the referenced remote, the locked commit may not exist in the remote\n", resolution.Uri, resolution.Remote, resolution.Source)
+		}
+	}
+	lock := g.GenerateLock()
+	if err := repoloader.SaveRepositoryLockToFile(lock, lockFile); err != nil {
+		logger.LeveledPrintf(log.LevelError, "Failed to save lock file [%s], error: %s\n", lockFile, err)
+		return cli.NewExitError("", 1)
+	}
+	for uri, entry := range lock.Repositories {
+		logger.LeveledPrintf(log.LevelDebug, "Locked repository [%s] at [%s]\n", uri, entry.Resolved)
+	}
+	logger.Printf("%d repositories locked to %s\n", len(lock.Repositories), lockFile)
+	// Done
+	return nil
+}
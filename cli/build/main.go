@@ -22,27 +22,134 @@ func GetCommand() []cli.Command {
 			Aliases:  []string{"lb"},
 			Usage:    "Force build with local dependencies. The same as 'op build --only-local' ",
 			Action:   LocalBuild,
-			Flags: []cli.Flag{
+			Flags: append([]cli.Flag{
 				cli.StringFlag{
 					Name:  "output, o",
 					Value: "build",
 					Usage: "The output path",
 				},
+				cli.StringFlag{
+					Name:  "output-mode",
+					Value: "symlink",
+					Usage: "How to put the artifacts into the output path, either symlink, copy or hardlink",
+				},
+				cli.StringFlag{
+					Name:  "tag-strategy",
+					Value: "random",
+					Usage: "The build tag strategy, either random, commit, describe, timestamp or template",
+				},
+				cli.StringFlag{
+					Name:  "tag-template",
+					Usage: "The go template of build tag for template strategy, e.g. {{.Describe}}-{{.Timestamp}}. Available fields: Random, Commit, Describe, Timestamp, Branch",
+				},
+				cli.BoolFlag{
+					Name:  "no-publish",
+					Usage: "Do not publish the artifacts even if publish is defined in target spec",
+				},
+				cli.BoolFlag{
+					Name:   "refuse-dirty",
+					Usage:  "Refuse to build the targets whose repository has uncommitted changes",
+					EnvVar: "OP_REFUSE_DIRTY",
+				},
+				cli.BoolFlag{
+					Name:  "submodules",
+					Usage: "Init and update the git submodules of the repositories to build recursively",
+				},
+				cli.BoolFlag{
+					Name:  "lfs",
+					Usage: "Fetch the git LFS objects of the repositories to build",
+				},
+				cli.StringFlag{
+					Name:   "lfs-store",
+					Usage:  "The local git LFS store shared by all repositories, empty means the default store of each repository",
+					EnvVar: "OP_LFS_STORE",
+				},
+				cli.StringFlag{
+					Name:  "trace",
+					Usage: "Write the spans of loading and building (repositories, targets and build stages) to the file, e.g. trace.json",
+				},
+				cli.StringFlag{
+					Name:  "trace-format",
+					Value: "chrome",
+					Usage: "The format of the trace file. Available values: chrome (the chrome trace-event file, open by chrome://tracing), timeline (the json timeline)",
+				},
+				cli.BoolFlag{
+					Name:  "update-lock",
+					Usage: "Ignore the current lock file and update it with the resolved repositories after the graph is loaded. The repositories resolved from local (by finder or overwrite) are not updated, use op lock to lock them explicitly",
+				},
 				cli.BoolFlag{
-					Name:  "disable-finder",
-					Usage: "Disable the repository local finder",
+					Name:  "auto-clean",
+					Usage: "Clean the build data by the retention flags (keep-last, keep-within, keep-referenced) after a successful build. The current build is always kept, and the last 5 and referenced builds are kept if no retention flag specified",
+				},
+			}, append(graphFlags, retentionFlags...)...),
+		},
+		{
+			Category: "Builder",
+			Name:     "affected",
+			Usage:    "Output (or build) the targets of current repository affected by the files changed since a commit",
+			Action:   Affected,
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:  "since",
+					Usage: "The commit (or any revision) to diff with, e.g. origin/master. The working tree is diffed from the merge base of the commit and HEAD (as git diff origin/master...)",
 				},
-				cli.StringSliceFlag{
-					Name:  "repository-remote-overwrite, w",
-					Usage: "Overwrite the repository remote (or local path). Format: uri:path",
+				cli.BoolFlag{
+					Name:  "build",
+					Usage: "Build the affected targets instead of output them",
+				},
+				cli.StringFlag{
+					Name:  "output, o",
+					Value: "build",
+					Usage: "The output path of the build",
+				},
+			}, graphFlags...),
+		},
+		{
+			Category: "Graph",
+			Name:     "graph",
+			Usage:    "Query the sourcecode graph of current repository",
+			Subcommands: []cli.Command{
+				{
+					Name:      "rdeps",
+					Usage:     "Output the targets (in the graph of current repository and the --root repositories) depending on the target (transitively) in topological order",
+					ArgsUsage: "<target-uri>",
+					Action:    GraphRdeps,
+					Flags: append([]cli.Flag{
+						cli.BoolFlag{
+							Name:  "direct",
+							Usage: "Only output the targets depending on the target directly",
+						},
+						cli.StringSliceFlag{
+							Name:  "root",
+							Usage: "The other root repositories (remote or local path) to load, only the targets in current repository and these roots are searched",
+						},
+					}, graphFlags...),
 				},
 			},
 		},
+		{
+			Category: "Builder",
+			Name:     "lock",
+			Usage:    "Resolve every repository reference to an exact commit and write the lock file of current repository. The references are resolved by --resolution-policy and the resolution source is recorded",
+			Action:   Lock,
+			Flags: append([]cli.Flag{
+				cli.BoolFlag{
+					Name:  "check",
+					Usage: "Only check if the lock file is up to date with the spec, exit with error if it is stale",
+				},
+			}, graphFlags...),
+		},
 		{
 			Category: "Builder",
 			Name:     "clean-build",
-			Usage:    "Clean the build workspace. This will clean user ALL build data",
+			Usage:    "Clean the build workspace. This will clean user ALL build data unless any retention flag is specified",
 			Action:   Clean,
+			Flags: append([]cli.Flag{
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "Only report the builds to remove and the reclaimable space",
+				},
+			}, retentionFlags...),
 		},
 	}
 }
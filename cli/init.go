@@ -19,6 +19,7 @@ func GetWorkspace(c *cli.Context) (*workspace.Workspace, error) {
 	workDirUserPath := c.GlobalString("workdir-user-path")
 	workDirGlobalPath := c.GlobalString("workdir-global-path")
 	dockerUri := c.GlobalString("docker-uri")
+	finderSearchPaths := c.GlobalStringSlice("finder-search-path")
 	// Create workspace options
 	options := workspace.NewWorkspaceOptions()
 	options.Verbose = verbose
@@ -32,6 +33,7 @@ func GetWorkspace(c *cli.Context) (*workspace.Workspace, error) {
 		options.Dir.ProjectPath = workDirProjectPath
 	}
 	options.ThirdService.Docker.Uri = dockerUri
+	options.Finder.SearchPaths = finderSearchPaths
 	// Create workspace
 	ws, err := workspace.New(options, nil)
 	if err != nil {
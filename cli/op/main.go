@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"github.com/ops-openlight/openlight/cli/build"
 	"github.com/ops-openlight/openlight/cli/runner"
+	"github.com/ops-openlight/openlight/cli/spec"
 	"github.com/ops-openlight/openlight/pkg/workspace"
 	"gopkg.in/urfave/cli.v1"
 	"os"
@@ -56,6 +57,11 @@ func main() {
 			Value: workspace.DefaultDockerServiceUri,
 			Usage: "The docker daemon uri",
 		},
+		cli.StringSliceFlag{
+			Name:   "finder-search-path",
+			EnvVar: "OP_FINDER_SEARCH_PATH",
+			Usage:  "The search root (glob pattern is supported) of the paths and remote repository finders",
+		},
 	}
 	// Add commands from modules
 	for _, cmd := range build.GetCommand() {
@@ -64,6 +70,9 @@ func main() {
 	for _, cmd := range runner.GetCommand() {
 		app.Commands = append(app.Commands, cmd)
 	}
+	for _, cmd := range spec.GetCommand() {
+		app.Commands = append(app.Commands, cmd)
+	}
 	// Run it
 	app.Run(os.Args)
 }
@@ -0,0 +1,64 @@
+// Author: lipixun
+// Created Time : 三 12/28 11:46:02 2016
+//
+// File Name: lint.go
+// Description:
+//	Lint the repository spec files
+package spec
+
+import (
+	"fmt"
+	opcli "github.com/ops-openlight/openlight/cli"
+	"github.com/ops-openlight/openlight/pkg/log"
+	"github.com/ops-openlight/openlight/pkg/sourcecode/repoloader"
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"gopkg.in/urfave/cli.v1"
+	"path/filepath"
+)
+
+func Lint(c *cli.Context) error {
+	ws, err := opcli.GetWorkspace(c)
+	if err != nil {
+		return err
+	}
+	logger := ws.Logger.GetLoggerWithHeader(LogHeader)
+	// Get the files
+	filenames := []string(c.Args())
+	if len(filenames) == 0 {
+		rootPath, err := opcli.GetGitRootFromCurrentDirectory()
+		if err != nil {
+			logger.LeveledPrintf(log.LevelError, "Failed to get current git root directory (and which is required by empty file args), error: %s\n", err)
+			return cli.NewExitError("", 1)
+		}
+		filenames = append(filenames, filepath.Join(rootPath, spec.SpecFileName))
+	}
+	// Lint the files
+	count := 0
+	for _, filename := range filenames {
+		errs, err := repoloader.LintRepositorySpecFile(filename)
+		if err != nil {
+			logger.LeveledPrintf(log.LevelError, "Failed to lint spec file [%s], error: %s\n", filename, err)
+			return cli.NewExitError("", 1)
+		}
+		for _, specErr := range errs {
+			fmt.Printf("%s: %s\n", getSpecErrorPosition(filename, specErr), specErr.Error())
+		}
+		count += len(errs)
+	}
+	if count > 0 {
+		logger.LeveledPrintf(log.LevelError, "%d errors found\n", count)
+		return cli.NewExitError("", 1)
+	}
+	// Done
+	return nil
+}
+
+// Get the position of the spec error, format: file[:line[:column]], the unknown line and column are omitted
+func getSpecErrorPosition(filename string, specErr *spec.SpecError) string {
+	if specErr.Line <= 0 {
+		return filename
+	} else if specErr.Column <= 0 {
+		return fmt.Sprintf("%s:%d", filename, specErr.Line)
+	}
+	return fmt.Sprintf("%s:%d:%d", filename, specErr.Line, specErr.Column)
+}
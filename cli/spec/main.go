@@ -0,0 +1,33 @@
+// Author: lipixun
+// Created Time : 三 12/28 11:40:18 2016
+//
+// File Name: main.go
+// Description:
+//	The repository spec commands
+package spec
+
+import (
+	"gopkg.in/urfave/cli.v1"
+)
+
+const (
+	LogHeader = "CLI.Spec"
+)
+
+func GetCommand() []cli.Command {
+	return []cli.Command{
+		{
+			Category: "Spec",
+			Name:     "spec",
+			Usage:    "The repository spec tools",
+			Subcommands: []cli.Command{
+				{
+					Name:      "lint",
+					Usage:     "Check the repository spec files, will check the spec file of current repository if no file specified",
+					ArgsUsage: "[file...]",
+					Action:    Lint,
+				},
+			},
+		},
+	}
+}
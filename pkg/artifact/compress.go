@@ -0,0 +1,110 @@
+// Author: lipixun
+// Created Time : 一 12/26 10:26:05 2016
+//
+// File Name: compress.go
+// Description:
+//	The compress writers used by compress collect
+package artifact
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ops-openlight/openlight/pkg/util"
+	"io"
+)
+
+const (
+	CompressFormatTarGzip = "tar.gz"
+	CompressFormatTarZstd = "tar.zst"
+	CompressFormatZip     = "zip"
+
+	DefaultCompressLevel = -1 // Use the default level of the compress format
+)
+
+// Get all supported compress formats
+func GetCompressFormats() []string {
+	return []string{CompressFormatTarGzip, CompressFormatTarZstd, CompressFormatZip}
+}
+
+// The writer to write files into a compressed package
+type compressWriter interface {
+	WriteFile(path, name string) error // Write the file at path as name
+	Close() error                      // Close the writer, the underlying writer will not be closed
+}
+
+// Create a new compress writer
+// Parameters:
+// 	writer 		The underlying writer
+// 	format 		The compress format
+// 	level 		The compress level, DefaultCompressLevel means the default level of the format
+func newCompressWriter(writer io.Writer, format string, level int) (compressWriter, error) {
+	switch format {
+	case "", CompressFormatTarGzip:
+		if level == DefaultCompressLevel {
+			level = gzip.DefaultCompression
+		}
+		gzipWriter, err := gzip.NewWriterLevel(writer, level)
+		if err != nil {
+			return nil, err
+		}
+		return &tarCompressWriter{writer: tar.NewWriter(gzipWriter), compressor: gzipWriter}, nil
+	case CompressFormatTarZstd:
+		encoderLevel := zstd.SpeedDefault
+		if level != DefaultCompressLevel {
+			if level < 1 || level > 22 {
+				return nil, errors.New(fmt.Sprintf("Invalid zstd compress level [%d], must be in [1, 22]", level))
+			}
+			encoderLevel = zstd.EncoderLevelFromZstd(level)
+		}
+		zstdWriter, err := zstd.NewWriter(writer, zstd.WithEncoderLevel(encoderLevel))
+		if err != nil {
+			return nil, err
+		}
+		return &tarCompressWriter{writer: tar.NewWriter(zstdWriter), compressor: zstdWriter}, nil
+	case CompressFormatZip:
+		if level < flate.HuffmanOnly || level > flate.BestCompression {
+			return nil, errors.New(fmt.Sprintf("Invalid zip compress level [%d], must be in [%d, %d]", level, flate.HuffmanOnly, flate.BestCompression))
+		}
+		zipWriter := zip.NewWriter(writer)
+		zipWriter.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(out, level)
+		})
+		return &zipCompressWriter{writer: zipWriter}, nil
+	default:
+		return nil, errors.New(fmt.Sprintf("Unknown compress format [%s]", format))
+	}
+}
+
+type tarCompressWriter struct {
+	writer     *tar.Writer
+	compressor io.WriteCloser
+}
+
+func (this *tarCompressWriter) WriteFile(path, name string) error {
+	return util.TarWriteFile(path, name, this.writer)
+}
+
+func (this *tarCompressWriter) Close() error {
+	if err := this.writer.Close(); err != nil {
+		this.compressor.Close()
+		return err
+	}
+	return this.compressor.Close()
+}
+
+type zipCompressWriter struct {
+	writer *zip.Writer
+}
+
+func (this *zipCompressWriter) WriteFile(path, name string) error {
+	return util.ZipWriteFile(path, name, this.writer)
+}
+
+func (this *zipCompressWriter) Close() error {
+	return this.writer.Close()
+}
@@ -0,0 +1,163 @@
+// Author: lipixun
+// Created Time : 四 12/29 20:16:42 2016
+//
+// File Name: compress_test.go
+// Description:
+//
+package artifact
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var (
+	testCompressFiles = map[string]string{"a.txt": "a", "sub/b.txt": "b", "sub/deep/c.txt": "c"}
+)
+
+// Create a directory with the test files
+func newTestCompressDir(t *testing.T) string {
+	path, err := ioutil.TempDir("", "compress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range testCompressFiles {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(path, "root", name)), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(path, "root", name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+// Read the files in the package, key is file name, value is file content
+func readTestCompressPackage(t *testing.T, pkg, format string) map[string]string {
+	files := make(map[string]string)
+	if format == CompressFormatZip {
+		reader, err := zip.OpenReader(pkg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		for _, f := range reader.File {
+			r, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := ioutil.ReadAll(r)
+			r.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			files[f.Name] = string(data)
+		}
+		return files
+	}
+	pkgFile, err := os.Open(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pkgFile.Close()
+	var reader io.Reader
+	if format == CompressFormatTarZstd {
+		decoder, err := zstd.NewReader(pkgFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer decoder.Close()
+		reader = decoder
+	} else {
+		gzipReader, err := gzip.NewReader(pkgFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+	tarReader := tar.NewReader(reader)
+	for {
+		hdr, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(tarReader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[hdr.Name] = string(data)
+	}
+	return files
+}
+
+func TestCompressCollectFileArtifact(t *testing.T) {
+	for _, c := range []struct {
+		Format string
+		Level  int
+		Valid  bool
+	}{
+		{CompressFormatTarGzip, DefaultCompressLevel, true},
+		{CompressFormatTarGzip, 9, true},
+		{CompressFormatTarGzip, 10, false},
+		{CompressFormatTarZstd, DefaultCompressLevel, true},
+		{CompressFormatTarZstd, 19, true},
+		{CompressFormatTarZstd, 23, false},
+		{CompressFormatZip, DefaultCompressLevel, true},
+		{CompressFormatZip, 1, true},
+		{CompressFormatZip, 10, false},
+		{"rar", DefaultCompressLevel, false},
+	} {
+		path := newTestCompressDir(t)
+		options := NewDefaultCollectFileArtifactOptions()
+		options.Recursive = true
+		options.CompressFormat = c.Format
+		options.CompressLevel = c.Level
+		// The package is put into the collecting path, which should not be collected
+		pkg := filepath.Join(path, "root", "art."+c.Format)
+		art, err := CompressCollectFileArtifact("art", filepath.Join(path, "root"), pkg, options)
+		if !c.Valid {
+			if err == nil {
+				t.Errorf("Expect error when compressing by format [%s] level [%d]", c.Format, c.Level)
+			}
+			os.RemoveAll(path)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Failed to compress by format [%s] level [%d], error: %s", c.Format, c.Level, err)
+		}
+		if art.Name != "art" || art.Path != pkg || !art.Compressed || len(art.Files) != len(testCompressFiles) {
+			t.Errorf("Mismatch artifact of format [%s]. Actually [%#v]", c.Format, art)
+		}
+		if files := readTestCompressPackage(t, pkg, c.Format); !reflect.DeepEqual(files, testCompressFiles) {
+			t.Errorf("Mismatch files of format [%s]. Expected [%v] Actually [%v]", c.Format, testCompressFiles, files)
+		}
+		os.RemoveAll(path)
+	}
+}
+
+func TestCompressCollectSingleFileArtifact(t *testing.T) {
+	path := newTestCompressDir(t)
+	defer os.RemoveAll(path)
+	pkg := filepath.Join(path, "a.tar.gz")
+	art, err := CompressCollectFileArtifact("a", filepath.Join(path, "root", "a.txt"), pkg, NewDefaultCollectFileArtifactOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(art.Files, []string{"a.txt"}) {
+		t.Errorf("Mismatch files. Expected [[a.txt]] Actually [%v]", art.Files)
+	}
+	if files := readTestCompressPackage(t, pkg, CompressFormatTarGzip); !reflect.DeepEqual(files, map[string]string{"a.txt": "a"}) {
+		t.Errorf("Mismatch files in package. Expected [map[a.txt:a]] Actually [%v]", files)
+	}
+}
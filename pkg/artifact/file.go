@@ -7,8 +7,6 @@
 package artifact
 
 import (
-	"archive/tar"
-	"compress/gzip"
 	"errors"
 	"fmt"
 	"github.com/ops-openlight/openlight/pkg/util"
@@ -79,16 +77,20 @@ func (this *FileArtifact) String() string {
 
 // The collect options
 type CollectFileArtifactOptions struct {
-	Recursive     bool           // Recursive collect or not
-	FollowLink    bool           // Follow the symbol link or not. It's dangerous to enable this feature and thus not encouraged
-	Includes      *regexp.Regexp // The regexp to test the files to include
-	Excludes      *regexp.Regexp // The regexp to test the files to exclude
-	CompressLevel int            // The compress level when doing compress collect
+	Recursive      bool           // Recursive collect or not
+	FollowLink     bool           // Follow the symbol link or not. It's dangerous to enable this feature and thus not encouraged
+	Includes       *regexp.Regexp // The regexp to test the files to include
+	Excludes       *regexp.Regexp // The regexp to test the files to exclude
+	CompressFormat string         // The compress format when doing compress collect, tar.gz by default
+	CompressLevel  int            // The compress level when doing compress collect, the meaning of the level depends on the compress format
 }
 
 // Create the default options
 func NewDefaultCollectFileArtifactOptions() CollectFileArtifactOptions {
-	return CollectFileArtifactOptions{CompressLevel: gzip.DefaultCompression}
+	return CollectFileArtifactOptions{
+		CompressFormat: CompressFormatTarGzip,
+		CompressLevel:  DefaultCompressLevel,
+	}
 }
 
 // Collect file artifact
@@ -103,7 +105,9 @@ func CollectFileArtifact(name, path string, options CollectFileArtifactOptions)
 	files, err := listPath(path, &options)
 	if err != nil && err != pathIsAFileError {
 		return nil, err
-	} else if len(files) == 0 {
+	}
+	files = filterPath(files, &options)
+	if len(files) == 0 {
 		// No file collected
 		return nil, nil
 	}
@@ -120,44 +124,66 @@ func CollectFileArtifact(name, path string, options CollectFileArtifactOptions)
 // NOTE:
 //	- Directory will not be collected as a file, so empty directory will be ignored
 // 	- You can only either specify includes or excludes or neither of them but both
-//	- The files wll be compressed by gzip method
+//	- The files will be compressed by the compress format in options (tar.gz, tar.zst or zip)
+//	- The package file itself will never be collected even if it's located in the collecting path
 func CompressCollectFileArtifact(name, path, pkg string, options CollectFileArtifactOptions) (*FileArtifact, error) {
-	pkgFile, err := os.Create(pkg)
-	if err != nil {
-		return nil, err
-	}
-	defer pkgFile.Close()
-	gzipWriter, err := gzip.NewWriterLevel(pkgFile, options.CompressLevel)
-	if err != nil {
-		return nil, err
-	}
-	defer gzipWriter.Close()
-	tarWriter := tar.NewWriter(gzipWriter)
-	defer tarWriter.Close()
-	// Collect files
+	// Collect files before creating the package file
+	var sources []string // The source path of each file
 	files, err := listPath(path, &options)
 	if err != nil && err != pathIsAFileError {
 		return nil, err
 	} else if err == pathIsAFileError {
 		// A single file
-		if err := util.TarWriteFile(path, filepath.Base(path), tarWriter); err != nil {
-			return nil, err
-		}
-		files = append(files, filepath.Base(path))
+		files = []string{filepath.Base(path)}
+		sources = []string{path}
 	} else {
-		// Add files to tar
-		if len(files) == 0 {
-			// No files to compress
-			return nil, nil
+		files = filterPath(files, &options)
+		pkgPath, err := filepath.Abs(pkg)
+		if err != nil {
+			return nil, err
 		}
-		// Compress each file
+		var collectedFiles []string
 		for _, file := range files {
-			if err := util.TarWriteFile(filepath.Join(path, file), file, tarWriter); err != nil {
-				return nil, err
+			source := filepath.Join(path, file)
+			if sourcePath, err := filepath.Abs(source); err == nil && sourcePath == pkgPath {
+				// Skip the package file itself
+				continue
 			}
-			files = append(files, file)
+			collectedFiles = append(collectedFiles, file)
+			sources = append(sources, source)
+		}
+		files = collectedFiles
+	}
+	if len(files) == 0 {
+		// No files to compress
+		return nil, nil
+	}
+	// Create the package file
+	pkgFile, err := os.Create(pkg)
+	if err != nil {
+		return nil, err
+	}
+	writer, err := newCompressWriter(pkgFile, options.CompressFormat, options.CompressLevel)
+	if err != nil {
+		pkgFile.Close()
+		return nil, err
+	}
+	// Compress each file
+	for i, file := range files {
+		if err := writer.WriteFile(sources[i], file); err != nil {
+			writer.Close()
+			pkgFile.Close()
+			return nil, err
 		}
 	}
+	// Close the writers explicitly since the trailing data is written on close
+	if err := writer.Close(); err != nil {
+		pkgFile.Close()
+		return nil, err
+	}
+	if err := pkgFile.Close(); err != nil {
+		return nil, err
+	}
 	// Done
 	return NewFileArtifact(name, pkg, files, true), nil
 }
@@ -235,3 +261,24 @@ func listPath(path string, options *CollectFileArtifactOptions) ([]string, error
 		return nil, pathIsAFileError
 	}
 }
+
+// Filter the listed files by includes or excludes
+// Parameters:
+// 	files 			The relative path of the files
+// 	options 		The options to filter
+func filterPath(files []string, options *CollectFileArtifactOptions) []string {
+	if options.Includes == nil && options.Excludes == nil {
+		return files
+	}
+	var filteredFiles []string
+	for _, file := range files {
+		if options.Includes != nil && !options.Includes.MatchString(file) {
+			continue
+		}
+		if options.Excludes != nil && options.Excludes.MatchString(file) {
+			continue
+		}
+		filteredFiles = append(filteredFiles, file)
+	}
+	return filteredFiles
+}
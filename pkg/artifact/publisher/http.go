@@ -0,0 +1,108 @@
+// Author: lipixun
+// Created Time : 三 12/28 16:05:33 2016
+//
+// File Name: http.go
+// Description:
+//	The generic http artifact store, each file will be uploaded by a PUT request to:
+// 		<url>/<project>/<commit>/<tag>/<artifact name>/<file name>
+package publisher
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ops-openlight/openlight/pkg/artifact"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"time"
+)
+
+const (
+	HttpPublisherTimeoutSeconds = 600 // 10min for each file
+)
+
+type HttpArtifactPublisher struct {
+	url     *url.URL          // The root url of the store
+	headers map[string]string // The additional headers of each request
+	client  *http.Client
+}
+
+func NewHttpArtifactPublisher(rawurl string, headers map[string]string) (*HttpArtifactPublisher, error) {
+	if rawurl == "" {
+		return nil, errors.New("Require url")
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.New(fmt.Sprintf("Unsupported url scheme [%s]", u.Scheme))
+	}
+	return &HttpArtifactPublisher{
+		url:     u,
+		headers: headers,
+		client:  &http.Client{Timeout: HttpPublisherTimeoutSeconds * time.Second},
+	}, nil
+}
+
+func (this *HttpArtifactPublisher) Publish(art artifact.Artifact, version PublishVersion) (string, error) {
+	fileArtifact, ok := art.(*artifact.FileArtifact)
+	if !ok {
+		return "", errors.New(fmt.Sprintf("Unsupported artifact type [%s]", art.GetType()))
+	}
+	artifactPath := path.Join(version.Path(), art.GetName())
+	// Upload files
+	files := getPublishFiles(fileArtifact)
+	for _, file := range files {
+		if err := this.upload(file.Source, this.getUrl(path.Join(artifactPath, file.Name))); err != nil {
+			return "", err
+		}
+	}
+	// Done
+	if isSingleFileArtifact(fileArtifact) {
+		return this.getUrl(path.Join(artifactPath, files[0].Name)), nil
+	}
+	return this.getUrl(artifactPath), nil
+}
+
+// Get the url of the relative path
+func (this *HttpArtifactPublisher) getUrl(p string) string {
+	u := *this.url
+	u.Path = path.Join(u.Path, p)
+	return u.String()
+}
+
+// Upload a file to url
+func (this *HttpArtifactPublisher) upload(source, u string) error {
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, u, file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	for key, value := range this.headers {
+		req.Header.Set(key, value)
+	}
+	rsp, err := this.client.Do(req)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Failed to upload [%s] to [%s], error: %s", source, u, err))
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(rsp.Body)
+		return errors.New(fmt.Sprintf("Failed to upload [%s] to [%s], status: %s, response: %s", source, u, rsp.Status, string(body)))
+	}
+	// Done
+	return nil
+}
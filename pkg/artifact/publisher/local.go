@@ -0,0 +1,66 @@
+// Author: lipixun
+// Created Time : 三 12/28 15:42:10 2016
+//
+// File Name: local.go
+// Description:
+//	The local filesystem artifact store
+package publisher
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ops-openlight/openlight/pkg/artifact"
+	"github.com/ops-openlight/openlight/pkg/util"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+type LocalArtifactPublisher struct {
+	path string // The root path of the store
+}
+
+func NewLocalArtifactPublisher(path string) (*LocalArtifactPublisher, error) {
+	if path == "" {
+		return nil, errors.New("Require path")
+	}
+	realPath, err := util.GetRealPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalArtifactPublisher{path: realPath}, nil
+}
+
+// The root path of the store
+func (this *LocalArtifactPublisher) Path() string {
+	return this.path
+}
+
+func (this *LocalArtifactPublisher) Publish(art artifact.Artifact, version PublishVersion) (string, error) {
+	fileArtifact, ok := art.(*artifact.FileArtifact)
+	if !ok {
+		return "", errors.New(fmt.Sprintf("Unsupported artifact type [%s]", art.GetType()))
+	}
+	artifactPath := filepath.Join(this.path, filepath.FromSlash(version.Path()), art.GetName())
+	// Remove the previous published artifact of the same version
+	if err := os.RemoveAll(artifactPath); err != nil {
+		return "", err
+	}
+	// Copy files
+	files := getPublishFiles(fileArtifact)
+	for _, file := range files {
+		dest := filepath.Join(artifactPath, filepath.FromSlash(file.Name))
+		if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+			return "", err
+		}
+		if err := util.CopyFile(file.Source, dest); err != nil {
+			return "", errors.New(fmt.Sprintf("Failed to copy [%s] to [%s], error: %s", file.Source, dest, err))
+		}
+	}
+	// Done
+	publishedPath := artifactPath
+	if isSingleFileArtifact(fileArtifact) {
+		publishedPath = filepath.Join(artifactPath, files[0].Name)
+	}
+	return (&url.URL{Scheme: "file", Path: publishedPath}).String(), nil
+}
@@ -0,0 +1,68 @@
+// Author: lipixun
+// Created Time : 三 12/28 15:20:46 2016
+//
+// File Name: publisher.go
+// Description:
+//	The artifact publisher, publish artifacts to an artifact store
+//
+// 	The published artifacts are versioned by project, commit and tag:
+// 		<store root>/<project>/<commit>/<tag>/<artifact name>/...The artifact files...
+//
+package publisher
+
+import (
+	"github.com/ops-openlight/openlight/pkg/artifact"
+	"path"
+	"path/filepath"
+)
+
+const (
+	UnknownVersionValue = "unknown"
+)
+
+type ArtifactPublisher interface {
+	// Publish the artifact with version, returns the url of the published artifact
+	Publish(art artifact.Artifact, version PublishVersion) (string, error)
+}
+
+// The version of the published artifacts
+type PublishVersion struct {
+	Project string // The project name, usually the target key
+	Commit  string // The commit of the source code
+	Tag     string // The build tag
+}
+
+// Get the relative (slash separated) path of the version
+func (this PublishVersion) Path() string {
+	return path.Join(getVersionValue(this.Project), getVersionValue(this.Commit), getVersionValue(this.Tag))
+}
+
+func getVersionValue(value string) string {
+	if value == "" {
+		return UnknownVersionValue
+	}
+	return value
+}
+
+// A published file
+type publishFile struct {
+	Source string // The local source file path
+	Name   string // The slash separated relative name of the file in the published artifact
+}
+
+// Get the files to publish of a file artifact
+func getPublishFiles(art *artifact.FileArtifact) []publishFile {
+	if isSingleFileArtifact(art) {
+		return []publishFile{{Source: art.Path, Name: filepath.Base(art.Path)}}
+	}
+	var files []publishFile
+	for _, file := range art.Files {
+		files = append(files, publishFile{Source: filepath.Join(art.Path, file), Name: filepath.ToSlash(file)})
+	}
+	return files
+}
+
+// Check if the artifact is a single file
+func isSingleFileArtifact(art *artifact.FileArtifact) bool {
+	return art.Compressed || len(art.Files) == 0
+}
@@ -0,0 +1,115 @@
+// Author: lipixun
+// Created Time : 三 12/28 18:10:21 2016
+//
+// File Name: publisher_test.go
+// Description:
+//
+package publisher
+
+import (
+	"github.com/ops-openlight/openlight/pkg/artifact"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var (
+	publishVersion = PublishVersion{Project: "project", Commit: "commit", Tag: "tag"}
+)
+
+// Create a directory artifact with files
+func newTestArtifact(t *testing.T) (*artifact.FileArtifact, string) {
+	path, err := ioutil.TempDir("", "publisher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{"a.txt": "a", "sub/b.txt": "b"}
+	var names []string
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(path, name)), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(path, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	return artifact.NewFileArtifact("art", path, names, false), path
+}
+
+func TestHttpArtifactPublisher(t *testing.T) {
+	uploaded := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		uploaded[r.URL.Path] = string(data)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	art, path := newTestArtifact(t)
+	defer os.RemoveAll(path)
+	p, err := NewHttpArtifactPublisher(server.URL+"/store", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := p.Publish(art, publishVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != server.URL+"/store/project/commit/tag/art" {
+		t.Errorf("Incorrect published url [%s]", u)
+	}
+	for name, content := range map[string]string{"/store/project/commit/tag/art/a.txt": "a", "/store/project/commit/tag/art/sub/b.txt": "b"} {
+		if uploaded[name] != content {
+			t.Errorf("Incorrect uploaded file [%s]. Expect [%s] Actual [%s]", name, content, uploaded[name])
+		}
+	}
+}
+
+func TestHttpArtifactPublisherError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+	art, path := newTestArtifact(t)
+	defer os.RemoveAll(path)
+	p, err := NewHttpArtifactPublisher(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Publish(art, publishVersion); err == nil {
+		t.Error("Publish should fail when server rejects the upload")
+	}
+}
+
+func TestLocalArtifactPublisher(t *testing.T) {
+	art, path := newTestArtifact(t)
+	defer os.RemoveAll(path)
+	storePath, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(storePath)
+	p, err := NewLocalArtifactPublisher(storePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := p.Publish(art, publishVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	artifactPath := filepath.Join(p.Path(), "project", "commit", "tag", "art")
+	if u != "file://"+artifactPath {
+		t.Errorf("Incorrect published url [%s]", u)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(artifactPath, "sub", "b.txt"))
+	if err != nil || string(data) != "b" {
+		t.Errorf("Incorrect published file, data [%s] error: %v", string(data), err)
+	}
+}
@@ -15,19 +15,23 @@ import (
 	"regexp"
 )
 
+// Collect file artifacts by specs, the path of each spec is relative to path
 func CollectFileArtifactBySpecs(path string, specs map[string]*spec.FileArtifactCollectorSpec) ([]artifact.Artifact, error) {
 	var arts []artifact.Artifact
 	for name, artSpec := range specs {
-		art, err := CollectFileArtifactBySpec(name, filepath.Join(path, artSpec.Path), artSpec)
+		collectedArts, err := CollectFileArtifactBySpec(name, path, artSpec)
 		if err != nil {
 			return nil, err
 		}
-		arts = append(arts, art)
+		arts = append(arts, collectedArts...)
 	}
 	return arts, nil
 }
 
-func CollectFileArtifactBySpec(name, path string, artSpec *spec.FileArtifactCollectorSpec) (artifact.Artifact, error) {
+// Collect file artifacts by spec, the path of the spec is relative to path
+// Returns:
+// 	The collected artifacts, may contain both the uncompressed and compressed artifact if compress is defined in spec
+func CollectFileArtifactBySpec(name, path string, artSpec *spec.FileArtifactCollectorSpec) ([]artifact.Artifact, error) {
 	options := artifact.NewDefaultCollectFileArtifactOptions()
 	if artSpec.Includes != "" {
 		exp, err := regexp.Compile(artSpec.Includes)
@@ -45,6 +49,42 @@ func CollectFileArtifactBySpec(name, path string, artSpec *spec.FileArtifactColl
 	}
 	options.Recursive = artSpec.Recursive
 	options.FollowLink = artSpec.FollowLink
+	collectPath := filepath.Join(path, artSpec.Path)
 	// Collect
-	return artifact.CollectFileArtifact(name, path, options)
+	var arts []artifact.Artifact
+	if artSpec.Compress == nil || artSpec.Compress.Keep {
+		art, err := artifact.CollectFileArtifact(name, collectPath, options)
+		if err != nil {
+			return nil, err
+		}
+		if art != nil {
+			arts = append(arts, art)
+		}
+	}
+	if artSpec.Compress != nil {
+		// Compress collect
+		if artSpec.Compress.Format != "" {
+			options.CompressFormat = artSpec.Compress.Format
+		}
+		if artSpec.Compress.Level != nil {
+			options.CompressLevel = *artSpec.Compress.Level
+		}
+		pkgName := artSpec.Compress.Output
+		if pkgName == "" {
+			pkgName = fmt.Sprintf("%s.%s", name, options.CompressFormat)
+		}
+		artName := name
+		if artSpec.Compress.Keep {
+			artName = fmt.Sprintf("%s.compressed", name)
+		}
+		art, err := artifact.CompressCollectFileArtifact(artName, collectPath, filepath.Join(path, pkgName), options)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Failed to compress collect artifact [%s], error: %s", name, err))
+		}
+		if art != nil {
+			arts = append(arts, art)
+		}
+	}
+	// Done
+	return arts, nil
 }
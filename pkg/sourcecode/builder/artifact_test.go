@@ -0,0 +1,76 @@
+// Author: lipixun
+// Created Time : 四 12/29 20:45:13 2016
+//
+// File Name: artifact_test.go
+// Description:
+//
+package builder
+
+import (
+	"github.com/ops-openlight/openlight/pkg/artifact"
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCollectFileArtifactBySpecCompress(t *testing.T) {
+	path, err := ioutil.TempDir("", "collect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+	for _, name := range []string{"bin/a", "bin/b"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(path, name)), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(path, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []struct {
+		Compress *spec.FileArtifactCompressSpec
+		Expected map[string]string // The expected artifacts, key is artifact name, value is artifact path relative to path
+	}{
+		{nil, map[string]string{"bin": "bin"}},
+		{&spec.FileArtifactCompressSpec{}, map[string]string{"bin": "bin.tar.gz"}},
+		{&spec.FileArtifactCompressSpec{Format: artifact.CompressFormatZip}, map[string]string{"bin": "bin.zip"}},
+		{&spec.FileArtifactCompressSpec{Output: "dist/bin.tgz"}, map[string]string{"bin": "dist/bin.tgz"}},
+		{&spec.FileArtifactCompressSpec{Format: artifact.CompressFormatTarZstd, Keep: true}, map[string]string{"bin": "bin", "bin.compressed": "bin.tar.zst"}},
+	} {
+		if err := os.MkdirAll(filepath.Join(path, "dist"), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		arts, err := CollectFileArtifactBySpec("bin", path, &spec.FileArtifactCollectorSpec{Path: "bin", Recursive: true, Compress: c.Compress})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(arts) != len(c.Expected) {
+			t.Errorf("Mismatch artifact count of compress spec [%#v]. Expected [%d] Actually [%d]", c.Compress, len(c.Expected), len(arts))
+			continue
+		}
+		for _, art := range arts {
+			fileArt := art.(*artifact.FileArtifact)
+			expectedPath, ok := c.Expected[fileArt.Name]
+			if !ok {
+				t.Errorf("Unexpected artifact [%s] of compress spec [%#v]", fileArt.Name, c.Compress)
+				continue
+			}
+			if fileArt.Path != filepath.Join(path, expectedPath) {
+				t.Errorf("Mismatch path of artifact [%s]. Expected [%s] Actually [%s]", fileArt.Name, filepath.Join(path, expectedPath), fileArt.Path)
+			}
+			if fileArt.Compressed != (expectedPath != "bin") {
+				t.Errorf("Mismatch compressed of artifact [%s]. Actually [%v]", fileArt.Name, fileArt.Compressed)
+			}
+			if len(fileArt.Files) != 2 {
+				t.Errorf("Mismatch files of artifact [%s]. Actually [%v]", fileArt.Name, fileArt.Files)
+			}
+			if fileArt.Compressed {
+				if _, err := os.Stat(fileArt.Path); err != nil {
+					t.Errorf("Package of artifact [%s] not created, error: %s", fileArt.Name, err)
+				}
+			}
+		}
+	}
+}
@@ -11,8 +11,11 @@
 // 			c. Until all packages are linked
 // 		2. Build stage:
 // 			a. Recursively build all targets with build spec defined, and collect the artifact
-// 		3. [Optional] Copy stage:
-// 			a. Copy the artifacts to output directory
+// 		3. Publish stage:
+// 			a. Recursively publish the artifacts of the built targets with publish spec defined
+// 		4. [Optional] Copy stage:
+// 			a. Copy (symlink, copy or hardlink, depends on the output mode) the artifacts of the target and its built dependencies to output directory
+// 			b. Write the build result (with the repository metadata and the timing summary) as manifest.json
 //
 // 	The environment struct
 //		buildTempDir/
@@ -32,6 +35,7 @@
 package builder
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/ops-openlight/openlight/pkg/artifact"
@@ -39,10 +43,14 @@ import (
 	"github.com/ops-openlight/openlight/pkg/sourcecode"
 	"github.com/ops-openlight/openlight/pkg/sourcecode/graph"
 	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"github.com/ops-openlight/openlight/pkg/util"
 	"github.com/ops-openlight/openlight/pkg/workspace"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
+	"time"
 )
 
 const (
@@ -50,6 +58,8 @@ const (
 
 	BuilderEnvironmentDirName = "environs"
 	BuilderOutputDirName      = "output"
+	BuilderOutputDepsDirName  = "deps"
+	BuilderManifestFileName   = "manifest.json" // The build result (with the metadata) of the target in output directory
 
 	BuilderDefaultArtifactName = "default"
 )
@@ -66,14 +76,17 @@ var (
 )
 
 type Builder struct {
-	graph           *graph.Graph
-	logger          log.Logger
-	path            string // The build temp path
-	Options         BuilderOptions
-	Results         map[string]*spec.BuildResult // The global build results, key is target key
-	Environments    map[string]Environment       // The environments, key is build type
-	preparedTargets map[string]bool              // The prepare targets
-	builtTargets    map[string]bool              // The build targets
+	graph            *graph.Graph
+	logger           log.Logger
+	path             string // The build temp path
+	Options          BuilderOptions
+	Results          map[string]*spec.BuildResult // The global build results, key is target key
+	Environments     map[string]Environment       // The environments, key is build type
+	preparedTargets  map[string]bool              // The prepare targets
+	builtTargets     map[string]bool              // The build targets
+	publishedTargets map[string]bool              // The published targets
+	timings          map[string]*targetTiming     // The timings of the targets, key is target key
+	building         string                       // The key of the target in building
 }
 
 // Create a new Builder
@@ -84,21 +97,31 @@ func New(graph *graph.Graph, options BuilderOptions) (*Builder, error) {
 	if options.Tag == "" {
 		return nil, errors.New("Require tag")
 	}
-	// Get the build path
-	path, err := graph.Workspace().Dir.User.GetPath(filepath.Join("sourcecode", "builder", options.Tag))
+	// Create the build path, the previous build data of the same tag (e.g. derived from commit) is kept
+	rootPath, err := GetBuildDataPath(graph.Workspace())
 	if err != nil {
 		return nil, err
 	}
+	path, err := createBuildPath(rootPath, options.Tag)
+	if err != nil {
+		return nil, err
+	}
+	// Write the build info, which is used by build data garbage collection
+	if err := writeBuildInfo(path, BuildInfo{Tag: options.Tag, Time: options.Time, OutputPath: options.OutputPath}); err != nil {
+		return nil, err
+	}
 	// Create Builder
 	return &Builder{
-		graph:           graph,
-		logger:          graph.Workspace().Logger.GetLoggerWithHeader(BuilderLogHeader),
-		path:            path,
-		Options:         options,
-		Results:         make(map[string]*spec.BuildResult),
-		Environments:    make(map[string]Environment),
-		preparedTargets: make(map[string]bool),
-		builtTargets:    make(map[string]bool),
+		graph:            graph,
+		logger:           graph.Workspace().Logger.GetLoggerWithHeader(BuilderLogHeader),
+		path:             path,
+		Options:          options,
+		Results:          make(map[string]*spec.BuildResult),
+		Environments:     make(map[string]Environment),
+		preparedTargets:  make(map[string]bool),
+		builtTargets:     make(map[string]bool),
+		publishedTargets: make(map[string]bool),
+		timings:          make(map[string]*targetTiming),
 	}, nil
 }
 
@@ -130,6 +153,7 @@ func (this *Builder) Build(target *spec.Target) (*spec.BuildResult, error) {
 	if result := this.Results[target.Key()]; result != nil {
 		return result, nil
 	}
+	this.building = target.Key()
 	var err error
 	// Stage 1. Prepare
 	err = this.graph.Traverse(
@@ -169,21 +193,64 @@ func (this *Builder) Build(target *spec.Target) (*spec.BuildResult, error) {
 	if err != nil {
 		return nil, err
 	}
-	// Stage 3. Copy
+	// Stage 3. Publish
+	if !this.Options.NoPublish {
+		err = this.graph.Traverse(
+			target,
+			this.publishGraphTraverseVisitor,
+			this.buildGraphTraverseController,
+			nil,
+			false,
+			newBuilderContext(this),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Stage 4. Copy
 	if this.Options.OutputPath != "" {
-		if err := this.copy2Output(target); err != nil {
+		span := this.Options.TraceRecorder.Start(sourcecode.TraceTypeCopy, target.Key(), target.Key())
+		startTime := time.Now()
+		err := this.copy2Output(target)
+		this.getTiming(target).copy = time.Now().Sub(startTime)
+		span.Finish(err)
+		if err != nil {
 			return nil, errors.New(fmt.Sprintf("Failed to copy artifact to output, error: %s", err))
 		}
+		// Write the manifest with the timing summary
+		if buildResult := this.Results[target.Key()]; buildResult != nil {
+			summary, err := this.GetBuildSummary(target)
+			if err != nil {
+				return nil, err
+			}
+			manifest := *buildResult
+			manifest.Summary = summary
+			if err := writeBuildManifest(&manifest, filepath.Join(this.Options.OutputPath, target.Name, BuilderManifestFileName)); err != nil {
+				return nil, errors.New(fmt.Sprintf("Failed to write build manifest, error: %s", err))
+			}
+		}
 	}
 	// Get the build result of the target and return
 	result := this.Results[target.Key()]
 	return result, nil
 }
 
-func (this *Builder) prepareGraphTraverseVisitor(target *spec.Target, from *spec.Target, by *spec.TargetDependencySpec, context interface{}) error {
+func (this *Builder) prepareGraphTraverseVisitor(target *spec.Target, from *spec.Target, by *spec.TargetDependencySpec, context interface{}) (err error) {
 	if !this.preparedTargets[target.Key()] {
+		span := this.Options.TraceRecorder.Start(sourcecode.TraceTypePrepare, target.Key(), target.Key())
+		defer func() {
+			span.Finish(err)
+		}()
+		startTime := time.Now()
 		ctx := context.(*BuilderContext)
 		this.logger.LeveledPrintf(log.LevelInfo, "Preparing %s\n", ctx.Tracer.String())
+		if this.Options.RefuseDirty && target.Repository.Metadata.Dirty {
+			return errors.New(fmt.Sprintf("Refuse to build target [%s] since repository [%s] has uncommitted changes: %s",
+				target.Key(), target.Repository.Uri, strings.Join(target.Repository.Metadata.ModifiedFiles, ", ")))
+		}
+		if submodule := target.Repository.Local.GetSubmodule(target.Path()); submodule != nil && !submodule.Initialized {
+			return errors.New(fmt.Sprintf("Target [%s] is inside the git submodule [%s] which is not initialized", target.Key(), submodule.Path))
+		}
 		builder := SourceCodeBuilders[target.Spec.Build.Type]
 		if builder == nil {
 			return errors.New(fmt.Sprintf("Builder [%s] not found", target.Spec.Build.Type))
@@ -199,14 +266,20 @@ func (this *Builder) prepareGraphTraverseVisitor(target *spec.Target, from *spec
 			return err
 		}
 		// Good, set prepared
+		this.getTiming(target).prepare = time.Now().Sub(startTime)
 		this.preparedTargets[target.Key()] = true
 	}
 	// Has already prepared
 	return nil
 }
 
-func (this *Builder) buildGraphTraverseVisitor(target *spec.Target, from *spec.Target, by *spec.TargetDependencySpec, context interface{}) error {
+func (this *Builder) buildGraphTraverseVisitor(target *spec.Target, from *spec.Target, by *spec.TargetDependencySpec, context interface{}) (err error) {
 	if !this.builtTargets[target.Key()] {
+		span := this.Options.TraceRecorder.Start(sourcecode.TraceTypeBuild, target.Key(), target.Key())
+		defer func() {
+			span.Finish(err)
+		}()
+		startTime := time.Now()
 		ctx := context.(*BuilderContext)
 		this.logger.LeveledPrintf(log.LevelInfo, "Building %s\n", ctx.Tracer.String())
 		builder := SourceCodeBuilders[target.Spec.Build.Type]
@@ -224,6 +297,9 @@ func (this *Builder) buildGraphTraverseVisitor(target *spec.Target, from *spec.T
 			return err
 		}
 		// Good, set built
+		timing := this.getTiming(target)
+		timing.build = time.Now().Sub(startTime)
+		timing.builtBy = this.building
 		this.builtTargets[target.Key()] = true
 	}
 	// Has already built
@@ -235,50 +311,135 @@ func (this *Builder) buildGraphTraverseController(dep *spec.TargetDependencySpec
 	return dep.Options.Build
 }
 
+func (this *Builder) publishGraphTraverseVisitor(target *spec.Target, from *spec.Target, by *spec.TargetDependencySpec, context interface{}) (err error) {
+	if !this.publishedTargets[target.Key()] {
+		span := this.Options.TraceRecorder.Start(sourcecode.TraceTypePublish, target.Key(), target.Key())
+		defer func() {
+			span.Finish(err)
+		}()
+		buildResult := this.Results[target.Key()]
+		if len(target.Spec.Publish) > 0 && buildResult != nil {
+			this.logger.LeveledPrintf(log.LevelInfo, "Publishing %s\n", target.Key())
+			if err := PublishBuildResult(target, buildResult); err != nil {
+				return err
+			}
+		}
+		// Good, set published
+		this.publishedTargets[target.Key()] = true
+	}
+	// Has already published
+	return nil
+}
+
 // Copy to output path
 func (this *Builder) copy2Output(target *spec.Target) error {
+	mode := this.Options.OutputMode
+	if mode == "" {
+		mode = DefaultOutputMode
+	}
+	if !IsValidOutputMode(mode) {
+		return errors.New(fmt.Sprintf("Unknown output mode [%s]", mode))
+	}
 	// Check output path
 	if err := os.MkdirAll(this.Options.OutputPath, os.ModePerm); err != nil {
 		return err
 	}
-	// Link to output
+	// Output the build result
 	buildResult := this.Results[target.Key()]
 	if buildResult != nil {
-		for _, art := range buildResult.Artifacts {
-			if art.GetType() == artifact.ArtifactTypeFile {
-				fileArtifact, ok := art.(*artifact.FileArtifact)
-				if !ok {
-					return errors.New("Cannot convert artifact to file artifact")
-				}
-				targetFile := filepath.Join(this.Options.OutputPath, target.Name, art.GetName())
-				if fileArtifact.Compressed || fileArtifact.Files == nil {
-					// The file artifact is a single file, add the file name
-					targetFile = filepath.Join(targetFile, filepath.Base(fileArtifact.Path))
-				}
-				if _, err := os.Stat(targetFile); err == nil {
-					// Remove it
-					if err := os.Remove(targetFile); err != nil {
-						return err
-					}
-				} else if !os.IsNotExist(err) {
-					return err
-				}
-				// Ensure the target file directory
-				targetDir := filepath.Dir(targetFile)
-				if err := os.MkdirAll(targetDir, os.ModePerm); err != nil {
-					return err
-				}
-				// Link
-				if err := os.Symlink(fileArtifact.Path, targetFile); err != nil {
+		path := filepath.Join(this.Options.OutputPath, target.Name)
+		if err := this.outputBuildResult(buildResult, path, mode); err != nil {
+			return err
+		}
+	}
+	// Done
+	return nil
+}
+
+// Output the file artifacts of the build result and its dependencies to path
+// The layout:
+// 	path/
+// 		<artifact name>/...The artifact files...
+// 		deps/
+// 			<dependency name>/...The same layout as path...
+func (this *Builder) outputBuildResult(buildResult *spec.BuildResult, path string, mode string) error {
+	for _, art := range buildResult.Artifacts {
+		if art.GetType() != artifact.ArtifactTypeFile {
+			continue
+		}
+		fileArtifact, ok := art.(*artifact.FileArtifact)
+		if !ok {
+			return errors.New("Cannot convert artifact to file artifact")
+		}
+		artifactPath := filepath.Join(path, art.GetName())
+		// Remove the previous output of this artifact
+		if err := os.RemoveAll(artifactPath); err != nil {
+			return err
+		}
+		if fileArtifact.Compressed || len(fileArtifact.Files) == 0 {
+			// The file artifact is a single file, add the file name
+			if err := this.outputFile(fileArtifact.Path, filepath.Join(artifactPath, filepath.Base(fileArtifact.Path)), mode); err != nil {
+				return err
+			}
+		} else {
+			// The file artifact is a directory, output each file
+			for _, file := range fileArtifact.Files {
+				if err := this.outputFile(filepath.Join(fileArtifact.Path, file), filepath.Join(artifactPath, file), mode); err != nil {
 					return err
 				}
 			}
 		}
 	}
+	// Output the dependencies
+	for name, depBuildResult := range buildResult.Deps {
+		if err := this.outputBuildResult(depBuildResult, filepath.Join(path, BuilderOutputDepsDirName, name), mode); err != nil {
+			return err
+		}
+	}
 	// Done
 	return nil
 }
 
+// Write the build result as manifest
+func writeBuildManifest(buildResult *spec.BuildResult, filename string) error {
+	data, err := json.MarshalIndent(buildResult, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+// Output a single file from source to dest by mode
+func (this *Builder) outputFile(source, dest string, mode string) error {
+	// Ensure the dest directory
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+	switch mode {
+	case OutputModeSymlink:
+		return os.Symlink(source, dest)
+	case OutputModeHardlink:
+		// Link the real file since the source may be a symbol link
+		realSource, err := filepath.EvalSymlinks(source)
+		if err != nil {
+			return err
+		}
+		if err := os.Link(realSource, dest); err != nil {
+			// Usually cross device link, fallback to copy
+			this.logger.LeveledPrintf(log.LevelDebug, "Failed to hardlink [%s] to [%s], fallback to copy, error: %s\n", source, dest, err)
+			return util.CopyFile(realSource, dest)
+		}
+		return nil
+	case OutputModeCopy:
+		return util.CopyFile(source, dest)
+	default:
+		return errors.New(fmt.Sprintf("Unknown output mode [%s]", mode))
+	}
+}
+
 // Get the environment of build type t
 func (this *Builder) GetEnvironment(t string) (Environment, error) {
 	environ := this.Environments[t]
@@ -362,7 +523,7 @@ type SourceCodeBuilder interface {
 
 // Clean all build data
 func CleanBuildData(ws *workspace.Workspace) error {
-	path, err := ws.Dir.User.GetPath(filepath.Join("sourcecode", "builder"))
+	path, err := GetBuildDataPath(ws)
 	if err != nil {
 		return err
 	}
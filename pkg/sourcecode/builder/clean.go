@@ -0,0 +1,233 @@
+// Author: lipixun
+// Created Time : 四 12/29 11:02:37 2016
+//
+// File Name: clean.go
+// Description:
+//	The build data garbage collection
+//
+// 	Each build leaves a build directory (named by the build tag, with a random suffix if the tag is used by another build) under the builder root path,
+// 	the build info file (build.json) in the build directory records the build tag, time and output path.
+// 	A build is kept if any of the following retention policies matches:
+// 		- It's one of the last N builds
+// 		- It's younger than a duration
+// 		- It's referenced by the symbol links in its output path
+// 		- It's explicitly specified to keep (e.g. the current build)
+//
+package builder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ops-openlight/openlight/pkg/workspace"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	BuilderInfoFileName = "build.json"
+)
+
+var (
+	// The default retention policy of the automatic cleanup if no keep rule is specified
+	DefaultRetentionPolicy = RetentionPolicy{KeepLast: 5, KeepReferenced: true}
+)
+
+// The build info, will be written into the build directory
+type BuildInfo struct {
+	Tag        string    `json:"tag"`
+	Time       time.Time `json:"time"`
+	OutputPath string    `json:"outputPath"`
+}
+
+// The build data of a build
+type BuildData struct {
+	Info       BuildInfo // The build info, the tag and time will be the name and modification time of the build directory if no build info file found
+	Path       string    // The build path
+	Size       int64     // The size (in bytes) of the build directory, the symbol links are not followed
+	Referenced bool      // Referenced by the symbol links in the output path or not
+}
+
+// The retention policy
+type RetentionPolicy struct {
+	KeepLast       int           // Keep the last N builds, 0 means not to keep by count
+	KeepWithin     time.Duration // Keep the builds younger than this duration, 0 means not to keep by age
+	KeepReferenced bool          // Keep the builds referenced by the symbol links in their output path
+	KeepTags       []string      // Keep the builds of these tags
+}
+
+// Check if the policy keeps nothing
+func (this *RetentionPolicy) IsEmpty() bool {
+	return this.KeepLast <= 0 && this.KeepWithin <= 0 && !this.KeepReferenced && len(this.KeepTags) == 0
+}
+
+// The clean result
+type CleanResult struct {
+	Removed        []*BuildData // The removed (or will be removed when dry run) builds
+	Kept           []*BuildData // The kept builds
+	ReclaimedBytes int64        // The reclaimed (or reclaimable when dry run) size in bytes
+}
+
+// Get the builder root path which contains all build directories
+func GetBuildDataPath(ws *workspace.Workspace) (string, error) {
+	return ws.Dir.User.GetPath(filepath.Join("sourcecode", "builder"))
+}
+
+// Create the build path of the tag under the root path
+// The previous build data of the same tag is kept since its outputs may be still referenced (e.g. symlinked) or in building,
+// so a random suffix is added to the directory name (not the tag) if the tag has been used
+func createBuildPath(rootPath, tag string) (string, error) {
+	name := tag
+	for {
+		path := filepath.Join(rootPath, name)
+		if err := os.Mkdir(path, os.ModePerm); err == nil {
+			return path, nil
+		} else if !os.IsExist(err) {
+			return "", err
+		}
+		suffix, err := NewTag()
+		if err != nil {
+			return "", err
+		}
+		name = fmt.Sprintf("%s.%s", tag, suffix)
+	}
+}
+
+// Write the build info into the build path
+func writeBuildInfo(path string, info BuildInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(path, BuilderInfoFileName), data, 0644)
+}
+
+// List all build data, sorted by build time from the newest to the oldest
+func ListBuildData(ws *workspace.Workspace) ([]*BuildData, error) {
+	rootPath, err := GetBuildDataPath(ws)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := ioutil.ReadDir(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	var builds []*BuildData
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		build := &BuildData{
+			Info: BuildInfo{Tag: info.Name(), Time: info.ModTime()},
+			Path: filepath.Join(rootPath, info.Name()),
+		}
+		if data, err := ioutil.ReadFile(filepath.Join(build.Path, BuilderInfoFileName)); err == nil {
+			var buildInfo BuildInfo
+			if err := json.Unmarshal(data, &buildInfo); err == nil {
+				if buildInfo.Tag != "" {
+					build.Info.Tag = buildInfo.Tag
+				}
+				build.Info.Time = buildInfo.Time
+				build.Info.OutputPath = buildInfo.OutputPath
+			}
+		}
+		if build.Size, err = getPathSize(build.Path); err != nil {
+			return nil, err
+		}
+		builds = append(builds, build)
+	}
+	// Check the references
+	for _, build := range builds {
+		if build.Info.OutputPath != "" {
+			markReferencedBuilds(build.Info.OutputPath, builds)
+		}
+	}
+	// Sort
+	sort.Slice(builds, func(i, j int) bool {
+		return builds[i].Info.Time.After(builds[j].Info.Time)
+	})
+	// Done
+	return builds, nil
+}
+
+// Clean the build data by retention policy
+// Parameters:
+// 	ws 			The workspace
+// 	policy 		The retention policy
+// 	dryRun 		Only report the builds to remove, don't remove them
+// NOTE: At least one keep rule is required to remove the builds, use CleanBuildData to remove all build data
+func CleanBuildDataByPolicy(ws *workspace.Workspace, policy RetentionPolicy, dryRun bool) (*CleanResult, error) {
+	if policy.IsEmpty() && !dryRun {
+		return nil, errors.New("Require at least one keep rule of retention policy")
+	}
+	builds, err := ListBuildData(ws)
+	if err != nil {
+		return nil, err
+	}
+	keepTags := make(map[string]bool)
+	for _, tag := range policy.KeepTags {
+		keepTags[tag] = true
+	}
+	now := time.Now()
+	var result CleanResult
+	for i, build := range builds {
+		keep := keepTags[build.Info.Tag] ||
+			(policy.KeepLast > 0 && i < policy.KeepLast) ||
+			(policy.KeepWithin > 0 && now.Sub(build.Info.Time) < policy.KeepWithin) ||
+			(policy.KeepReferenced && build.Referenced)
+		if keep {
+			result.Kept = append(result.Kept, build)
+			continue
+		}
+		if !dryRun {
+			if err := os.RemoveAll(build.Path); err != nil {
+				return nil, err
+			}
+		}
+		result.Removed = append(result.Removed, build)
+		result.ReclaimedBytes += build.Size
+	}
+	// Done
+	return &result, nil
+}
+
+// Get the size of the path, the symbol links are not followed
+func getPathSize(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// Mark the builds referenced by the symbol links in the output path
+func markReferencedBuilds(outputPath string, builds []*BuildData) {
+	filepath.Walk(outputPath, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			return nil
+		}
+		dest, err := os.Readlink(p)
+		if err != nil {
+			return nil
+		}
+		for _, build := range builds {
+			if dest == build.Path || strings.HasPrefix(dest, build.Path+string(filepath.Separator)) {
+				build.Referenced = true
+			}
+		}
+		return nil
+	})
+}
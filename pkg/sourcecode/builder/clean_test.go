@@ -0,0 +1,179 @@
+// Author: lipixun
+// Created Time : 四 12/29 21:42:19 2016
+//
+// File Name: clean_test.go
+// Description:
+//
+package builder
+
+import (
+	"github.com/ops-openlight/openlight/pkg/workspace"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateBuildPath(t *testing.T) {
+	rootPath, err := ioutil.TempDir("", "buildpath")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootPath)
+	paths := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		path, err := createBuildPath(rootPath, "1a2b3c4")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 && path != filepath.Join(rootPath, "1a2b3c4") {
+			t.Errorf("Mismatch build path. Expected [%s] Actually [%s]", filepath.Join(rootPath, "1a2b3c4"), path)
+		} else if i > 0 && !strings.HasPrefix(filepath.Base(path), "1a2b3c4.") {
+			t.Errorf("Invalid build path [%s] of reused tag", path)
+		}
+		if paths[path] {
+			t.Errorf("Build path [%s] is reused", path)
+		}
+		paths[path] = true
+		if info, err := os.Stat(path); err != nil || !info.IsDir() {
+			t.Errorf("Build path [%s] not created", path)
+		}
+	}
+}
+
+// Create the builds (from the newest to the oldest) in a temp workspace, the build t3 is referenced by the output path
+func newTestBuildWorkspace(t *testing.T, tags []string) (*workspace.Workspace, string) {
+	path, err := ioutil.TempDir("", "buildclean")
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := workspace.NewWorkspaceOptions()
+	options.Dir.GlobalPath = filepath.Join(path, "global")
+	options.Dir.UserPath = filepath.Join(path, "user")
+	ws, err := workspace.New(options, nil)
+	if err != nil {
+		os.RemoveAll(path)
+		t.Fatal(err)
+	}
+	rootPath, err := GetBuildDataPath(ws)
+	if err != nil {
+		os.RemoveAll(path)
+		t.Fatal(err)
+	}
+	outputPath := filepath.Join(path, "output")
+	if err := os.MkdirAll(outputPath, os.ModePerm); err != nil {
+		os.RemoveAll(path)
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for i, tag := range tags {
+		buildPath, err := createBuildPath(rootPath, tag)
+		if err != nil {
+			os.RemoveAll(path)
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(buildPath, "bin"), []byte(tag), 0644); err != nil {
+			os.RemoveAll(path)
+			t.Fatal(err)
+		}
+		if err := writeBuildInfo(buildPath, BuildInfo{Tag: tag, Time: now.Add(-time.Duration(i+1) * time.Hour), OutputPath: outputPath}); err != nil {
+			os.RemoveAll(path)
+			t.Fatal(err)
+		}
+		if tag == "t3" {
+			if err := os.Symlink(filepath.Join(buildPath, "bin"), filepath.Join(outputPath, "bin")); err != nil {
+				os.RemoveAll(path)
+				t.Fatal(err)
+			}
+		}
+	}
+	return ws, path
+}
+
+func getTestBuildTags(builds []*BuildData) []string {
+	tags := []string{}
+	for _, build := range builds {
+		tags = append(tags, build.Info.Tag)
+	}
+	return tags
+}
+
+func TestListBuildData(t *testing.T) {
+	ws, path := newTestBuildWorkspace(t, []string{"t1", "t2", "t3", "t30"})
+	defer os.RemoveAll(path)
+	builds, err := ListBuildData(ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags := getTestBuildTags(builds); !reflect.DeepEqual(tags, []string{"t1", "t2", "t3", "t30"}) {
+		t.Errorf("Mismatch builds. Expected [t1 t2 t3 t30] Actually %v", tags)
+	}
+	// Only the build of the link destination is referenced (not the build with the same path prefix)
+	for _, build := range builds {
+		if build.Referenced != (build.Info.Tag == "t3") {
+			t.Errorf("Mismatch referenced of build [%s]. Actually [%v]", build.Info.Tag, build.Referenced)
+		}
+		// The size of the build info file and the built file
+		info, err := os.Stat(filepath.Join(build.Path, BuilderInfoFileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size := info.Size() + int64(len(build.Info.Tag)); build.Size != size {
+			t.Errorf("Mismatch size of build [%s]. Expected [%d] Actually [%d]", build.Info.Tag, size, build.Size)
+		}
+	}
+}
+
+func TestCleanBuildDataByPolicy(t *testing.T) {
+	ws, path := newTestBuildWorkspace(t, []string{"t1", "t2", "t3", "t4"})
+	defer os.RemoveAll(path)
+	for _, c := range []struct {
+		Policy  RetentionPolicy
+		Removed []string
+	}{
+		{RetentionPolicy{KeepLast: 1}, []string{"t2", "t3", "t4"}},
+		{RetentionPolicy{KeepLast: 5}, []string{}},
+		{RetentionPolicy{KeepWithin: 150 * time.Minute}, []string{"t3", "t4"}},
+		{RetentionPolicy{KeepReferenced: true}, []string{"t1", "t2", "t4"}},
+		{RetentionPolicy{KeepTags: []string{"t2"}}, []string{"t1", "t3", "t4"}},
+		{RetentionPolicy{KeepLast: 1, KeepReferenced: true, KeepTags: []string{"t4"}}, []string{"t2"}},
+		// Report all builds by the empty policy in dry run
+		{RetentionPolicy{}, []string{"t1", "t2", "t3", "t4"}},
+	} {
+		result, err := CleanBuildDataByPolicy(ws, c.Policy, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tags := getTestBuildTags(result.Removed); !reflect.DeepEqual(tags, c.Removed) {
+			t.Errorf("Mismatch removed builds of policy %+v. Expected %v Actually %v", c.Policy, c.Removed, tags)
+		}
+		var size int64
+		for _, build := range result.Removed {
+			size += build.Size
+		}
+		if result.ReclaimedBytes != size {
+			t.Errorf("Mismatch reclaimed bytes of policy %+v. Expected [%d] Actually [%d]", c.Policy, size, result.ReclaimedBytes)
+		}
+		if len(result.Kept)+len(result.Removed) != 4 {
+			t.Errorf("Mismatch kept builds of policy %+v. Actually %v", c.Policy, getTestBuildTags(result.Kept))
+		}
+	}
+	// The empty policy never removes builds
+	if _, err := CleanBuildDataByPolicy(ws, RetentionPolicy{}, false); err == nil {
+		t.Error("Expect error when cleaning build data by empty policy")
+	}
+	// Remove the builds
+	if _, err := CleanBuildDataByPolicy(ws, RetentionPolicy{KeepLast: 2}, false); err != nil {
+		t.Fatal(err)
+	}
+	builds, err := ListBuildData(ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags := getTestBuildTags(builds); !reflect.DeepEqual(tags, []string{"t1", "t2"}) {
+		t.Errorf("Mismatch builds after clean. Expected [t1 t2] Actually %v", tags)
+	}
+}
@@ -33,7 +33,7 @@ import (
 
 const (
 	DockerBuilderLogHeader = "DockerBuilder"
-	BuilderTypeDocker      = "docker"
+	BuilderTypeDocker      = spec.BuildTypeDocker
 
 	DefaultDockerFilename = "Dockerfile"
 
@@ -208,10 +208,15 @@ func (this *DockerSourceCodeBuilder) AddFileFromArtifact(target string, art arti
 }
 
 type DockerfileRecipient struct {
-	Tag    string
-	Time   string
-	Branch string
-	Commit string
+	Tag        string
+	Time       string
+	Branch     string
+	Commit     string
+	Dirty      bool
+	Author     string
+	CommitTime string
+	Remote     string
+	Tags       []string // The tags pointing at the commit
 }
 
 // Format the docker file
@@ -222,10 +227,15 @@ func (this *DockerSourceCodeBuilder) formatDockerfile(tempStr string, target *sp
 	}
 	// Create the recipient
 	recipient := DockerfileRecipient{
-		Tag:    context.Builder.Options.Tag,
-		Time:   context.Builder.Options.Time.Format(time.RFC3339),
-		Branch: target.Repository.Metadata.Branch,
-		Commit: target.Repository.Metadata.Commit,
+		Tag:        context.Builder.Options.Tag,
+		Time:       context.Builder.Options.Time.Format(time.RFC3339),
+		Branch:     target.Repository.Metadata.Branch,
+		Commit:     target.Repository.Metadata.Commit,
+		Dirty:      target.Repository.Metadata.Dirty,
+		Author:     target.Repository.Metadata.Author,
+		CommitTime: formatCommitTime(target.Repository.Metadata.CommitTime),
+		Remote:     target.Repository.Metadata.Remote,
+		Tags:       target.Repository.Metadata.Tags,
 	}
 	buf := new(bytes.Buffer)
 	if err := temp.Execute(buf, recipient); err != nil {
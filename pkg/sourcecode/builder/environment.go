@@ -114,19 +114,32 @@ func GeneralLink(target *spec.Target, link *spec.SourceCodeLink, dest string) er
 	if _, err := os.Stat(linkTargetName); err == nil {
 		return errors.New(fmt.Sprintf("Target [%s] already existed for target [%s] source [%s]", linkTargetName, target.Key(), link.Path))
 	} else if !os.IsNotExist(err) {
-		return errors.New(fmt.Sprintf("Failed to check link target for target [%s] source [%s] dest [%s], error: %s", target.Key(), link.Path, linkTargetName))
+		return errors.New(fmt.Sprintf("Failed to check link target for target [%s] source [%s] dest [%s], error: %s", target.Key(), link.Path, linkTargetName, err))
 	}
 	// Link it
 	return os.Symlink(filepath.Join(target.Path(), link.Path), linkTargetName)
 }
 
 // Get the standard build metadata environment variables
-func GetBuildMetadataEnvironVars(outputPath, branch, commit, tag string, t time.Time) []string {
+func GetBuildMetadataEnvironVars(outputPath string, metadata *spec.RepositoryMetadata, tag string, t time.Time) []string {
 	return []string{
 		fmt.Sprintf("CI_OUTPUT=%s", outputPath),
-		fmt.Sprintf("CI_BRANCH=%s", branch),
-		fmt.Sprintf("CI_COMMIT=%s", commit),
+		fmt.Sprintf("CI_BRANCH=%s", metadata.Branch),
+		fmt.Sprintf("CI_COMMIT=%s", metadata.Commit),
+		fmt.Sprintf("CI_COMMIT_AUTHOR=%s", metadata.Author),
+		fmt.Sprintf("CI_COMMIT_TIME=%s", formatCommitTime(metadata.CommitTime)),
+		fmt.Sprintf("CI_COMMIT_TAGS=%s", strings.Join(metadata.Tags, ",")),
+		fmt.Sprintf("CI_REMOTE=%s", metadata.Remote),
+		fmt.Sprintf("CI_DIRTY=%t", metadata.Dirty),
 		fmt.Sprintf("CI_TAG=%s", tag),
 		fmt.Sprintf("CI_TIME=%s", t.Format(time.RFC3339)),
 	}
 }
+
+// Format the commit time as RFC3339, returns empty string if the time is unknown
+func formatCommitTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -9,6 +9,11 @@
 // 			- buildCommit 	The build commit
 // 			- buildTime 	The build time in RFC3339 format
 // 			- buildTag 		The build tag
+// 			- buildDirty 	The working tree has uncommitted changes or not (true or false)
+// 			- buildCommitTime 	The commit time in RFC3339 format
+// 			- buildRemote 	The url of the origin remote (without user info)
+// 			- buildAuthor 	The author of the commit, format: name <email>
+// 			- buildTags 	The tags pointing at the commit, comma separated
 //			- buildGraph 	The build graph json string
 //
 package builder
@@ -30,7 +35,7 @@ import (
 const (
 	GolangLogHeader = "Golang"
 
-	BuilderTypeGolang = "golang"
+	BuilderTypeGolang = spec.BuildTypeGolang
 )
 
 type GolangSourceCodeBuilder struct{}
@@ -103,11 +108,16 @@ func (this *GolangSourceCodeBuilder) Build(target *spec.Target, env Environment,
 		return err
 	}
 	// The build metadata
-	args = append(args, "-ldflags", fmt.Sprintf("-X main.buildBranch=%s -X main.buildCommit=%s -X main.buildTime=%s -X main.buildTag=%s",
+	args = append(args, "-ldflags", fmt.Sprintf("-X main.buildBranch=%s -X main.buildCommit=%s -X main.buildTime=%s -X main.buildTag=%s -X main.buildDirty=%t -X main.buildCommitTime=%s -X main.buildRemote=%s %s %s",
 		target.Repository.Metadata.Branch,
 		target.Repository.Metadata.Commit,
 		context.Builder.Options.Time.Format(time.RFC3339),
 		context.Builder.Options.Tag,
+		target.Repository.Metadata.Dirty,
+		formatCommitTime(target.Repository.Metadata.CommitTime),
+		target.Repository.Metadata.Remote,
+		getGolangLdflagsVariable("buildAuthor", target.Repository.Metadata.Author),
+		getGolangLdflagsVariable("buildTags", strings.Join(target.Repository.Metadata.Tags, ",")),
 	))
 	// Add the build package
 	buildPackages := golangSpec.BuildPackages
@@ -234,3 +244,14 @@ func (this *GolangEnvironment) EnsurePackagePath(target *spec.Target) (string, e
 	this.targets[target.Key()] = environ
 	return packagePath, nil
 }
+
+// Get the -X flag of ldflags, the flag is quoted if the value contains spaces (e.g. the author)
+func getGolangLdflagsVariable(name, value string) string {
+	flag := fmt.Sprintf("main.%s=%s", name, value)
+	if !strings.ContainsAny(flag, " \t'\"") {
+		return "-X " + flag
+	} else if !strings.Contains(flag, "'") {
+		return fmt.Sprintf("-X '%s'", flag)
+	}
+	return fmt.Sprintf("-X \"%s\"", strings.Replace(flag, "\"", "", -1))
+}
@@ -7,23 +7,41 @@
 package builder
 
 import (
+	"github.com/ops-openlight/openlight/pkg/sourcecode"
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
 	"time"
 )
 
+const (
+	OutputModeSymlink  = "symlink"  // Symlink the artifacts into output path
+	OutputModeCopy     = "copy"     // Copy the artifacts into output path
+	OutputModeHardlink = "hardlink" // Hardlink the artifacts into output path, fallback to copy if failed to link
+
+	DefaultOutputMode = OutputModeSymlink
+)
+
 // The build option
 type BuilderOptions struct {
-	Tag        string            // The build tag
-	Time       time.Time         // The build time
-	OutputPath string            // The find build artifacts will be copied to this path
-	ThirdParty ThirdPartyOptions // The third party options
+	Tag           string                    // The build tag
+	TagStrategy   string                    // The strategy to generate the build tag, random by default
+	TagTemplate   string                    // The go template of the build tag, only used by template tag strategy
+	Time          time.Time                 // The build time
+	OutputPath    string                    // The find build artifacts will be copied to this path
+	OutputMode    string                    // How the artifacts are put into output path, symlink by default
+	NoPublish     bool                      // Do not publish the artifacts even if publish is defined in target spec
+	RefuseDirty   bool                      // Refuse to build the targets whose repository has uncommitted changes
+	ThirdParty    ThirdPartyOptions         // The third party options
+	TraceRecorder *sourcecode.TraceRecorder // Record the prepare, build, publish and copy spans of the targets, nil means no recording
 }
 
 // Create a new BuildOption
 func NewBuilderOptions(tag string, outputPath string) BuilderOptions {
 	return BuilderOptions{
-		Tag:        tag,
-		Time:       time.Now(),
-		OutputPath: outputPath,
+		Tag:         tag,
+		Time:        time.Now(),
+		OutputPath:  outputPath,
+		OutputMode:  DefaultOutputMode,
+		TagStrategy: DefaultTagStrategy,
 		ThirdParty: ThirdPartyOptions{
 			Docker: DockerOptions{
 				Push: true,
@@ -32,6 +50,21 @@ func NewBuilderOptions(tag string, outputPath string) BuilderOptions {
 	}
 }
 
+// Generate the build tag by the tag strategy, the tag is derived from the repository
+func (this *BuilderOptions) GenerateTag(repository *spec.Repository) error {
+	tag, err := NewTagByStrategy(this.TagStrategy, this.TagTemplate, repository, this.Time)
+	if err != nil {
+		return err
+	}
+	this.Tag = tag
+	return nil
+}
+
+// Check if the output mode is valid
+func IsValidOutputMode(mode string) bool {
+	return mode == OutputModeSymlink || mode == OutputModeCopy || mode == OutputModeHardlink
+}
+
 type ThirdPartyOptions struct {
 	Docker DockerOptions
 }
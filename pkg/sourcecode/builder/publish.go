@@ -0,0 +1,75 @@
+// Author: lipixun
+// Created Time : 三 12/28 17:25:40 2016
+//
+// File Name: publish.go
+// Description:
+//	Publish the artifacts of build result by target publish specs
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ops-openlight/openlight/pkg/artifact"
+	"github.com/ops-openlight/openlight/pkg/artifact/publisher"
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+)
+
+// Create the artifact publisher by spec
+func NewArtifactPublisherBySpec(publishSpec *spec.PublishSpec) (publisher.ArtifactPublisher, error) {
+	switch publishSpec.Type {
+	case spec.PublishTypeLocal:
+		if publishSpec.Local == nil {
+			return nil, errors.New("Local publish spec not defined")
+		}
+		return publisher.NewLocalArtifactPublisher(publishSpec.Local.Path)
+	case spec.PublishTypeHttp:
+		if publishSpec.Http == nil {
+			return nil, errors.New("Http publish spec not defined")
+		}
+		return publisher.NewHttpArtifactPublisher(publishSpec.Http.Url, publishSpec.Http.Headers)
+	default:
+		return nil, errors.New(fmt.Sprintf("Unknown publisher type [%s]", publishSpec.Type))
+	}
+}
+
+// Publish the artifacts of the build result by the publish specs of the target, the published urls will be added to the build result
+func PublishBuildResult(target *spec.Target, buildResult *spec.BuildResult) error {
+	version := publisher.PublishVersion{
+		Project: GetTargetRegularKey(target),
+		Commit:  buildResult.Metadata.Repository.Commit,
+		Tag:     buildResult.Metadata.Tag,
+	}
+	for _, publishSpec := range target.Spec.Publish {
+		p, err := NewArtifactPublisherBySpec(publishSpec)
+		if err != nil {
+			return err
+		}
+		// Get the artifacts to publish
+		var arts []artifact.Artifact
+		if len(publishSpec.Artifacts) == 0 {
+			for _, art := range buildResult.Artifacts {
+				if art.GetType() == artifact.ArtifactTypeFile {
+					arts = append(arts, art)
+				}
+			}
+		} else {
+			for _, name := range publishSpec.Artifacts {
+				art := buildResult.Artifacts[name]
+				if art == nil {
+					return errors.New(fmt.Sprintf("Artifact [%s] to publish not found", name))
+				}
+				arts = append(arts, art)
+			}
+		}
+		// Publish
+		for _, art := range arts {
+			u, err := p.Publish(art, version)
+			if err != nil {
+				return errors.New(fmt.Sprintf("Failed to publish artifact [%s] to [%s], error: %s", art.GetName(), publishSpec.Type, err))
+			}
+			buildResult.Published[art.GetName()] = append(buildResult.Published[art.GetName()], u)
+		}
+	}
+	// Done
+	return nil
+}
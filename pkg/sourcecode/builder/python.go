@@ -3,26 +3,39 @@
 //
 // File Name: python.go
 // Description:
-//	Python target, this target is used to build python binary via nuitka
+//	Python target, this target is used to build python package, wheel, virtualenv or binary via nuitka
 //  The normal python pip package could be simply built by makefile target or shell target
+// 		The build types:
+// 			- script 	Run the setup script, sdist by default
+// 			- nuitka 	Build binary or module via nuitka
+// 			- wheel 	Build PEP 517 wheel via pip
+// 			- venv 		Create a virtualenv, install the target and its python dependency targets, and package the virtualenv as a tarball
+// 						The virtualenv is created with copied interpreter and relative script shebangs, so it could be extracted to any path,
+// 						but it still requires the same base python installation (the home in pyvenv.cfg) and the activate scripts are not relocated
 // 		Will inject the following variables:
 // 			- buildBranch 	The build branch
 // 			- buildCommit 	The build commit
 // 			- buildTime 	The build time in RFC3339 format
 // 			- buildTag 		The build tag
+// 			- buildDirty 	The working tree has uncommitted changes or not
 //
 package builder
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"errors"
 	"fmt"
 	"github.com/ops-openlight/openlight/pkg/artifact"
 	"github.com/ops-openlight/openlight/pkg/log"
 	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"github.com/ops-openlight/openlight/pkg/util"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -31,19 +44,35 @@ const (
 	PythonLogHeader            = "Python"
 	PythonNuitkaLogHeader      = "Python.Nuitka"
 	PythonSetupScriptLogHeader = "Python.SetupScript"
+	PythonWheelLogHeader       = "Python.Wheel"
+	PythonVenvLogHeader        = "Python.Venv"
 
-	BuilderTypePython = "python"
+	BuilderTypePython = spec.BuildTypePython
 
-	PythonBuildTypeScript = "script"
-	PythonBuildTypeNuitka = "nuitka"
+	PythonBuildTypeScript = spec.PythonBuildTypeScript
+	PythonBuildTypeNuitka = spec.PythonBuildTypeNuitka
+	PythonBuildTypeWheel  = spec.PythonBuildTypeWheel
+	PythonBuildTypeVenv   = spec.PythonBuildTypeVenv
+
+	DefaultPythonInterpreter      = "python"
+	DefaultPythonRequirementsFile = "requirements.txt"
+
+	PythonVenvDirName = "venvs"
+	// The shebang to run the script by the python interpreter in the same directory, see distlib for details
+	PythonVenvRelocatableShebang = "#!/bin/sh\n'''exec' \"$(dirname -- \"$0\")/%s\" \"$0\" \"$@\"\n' '''\n"
 
 	PythonCodeInsertPointLine = "# [BUILD INSERT POINT]"
 
 	DefaultPythonSetupScriptFile    = "setup.py"
 	DefaultPythonSetupScriptCommand = "sdist"
 
-	PythonNuitkaBuildTypeBinary = "binary"
-	PythonNuitkaBuildTypeLib    = "lib"
+	PythonNuitkaBuildTypeBinary = spec.PythonNuitkaBuildTypeBinary
+	PythonNuitkaBuildTypeLib    = spec.PythonNuitkaBuildTypeLib
+)
+
+var (
+	// The extension modules and the __init__.py of their parent packages
+	PythonNuitkaLibOutputRegularExp = regexp.MustCompile("(\\.(so|pyd)|(^|[/\\\\])__init__\\.py)$")
 )
 
 type PythonSourceCodeBuilder struct{}
@@ -92,6 +121,10 @@ func (this *PythonSourceCodeBuilder) Build(target *spec.Target, env Environment,
 		return this.runScriptBuild(target, env, context)
 	} else if pythonSpec.Type == PythonBuildTypeNuitka {
 		return this.runNuitkaBuild(target, env, context)
+	} else if pythonSpec.Type == PythonBuildTypeWheel {
+		return this.runWheelBuild(target, env, context)
+	} else if pythonSpec.Type == PythonBuildTypeVenv {
+		return this.runVenvBuild(target, env, context)
 	} else {
 		return errors.New(fmt.Sprintf("Unknown python build type [%s]", pythonSpec.Type))
 	}
@@ -145,13 +178,12 @@ func (this *PythonSourceCodeBuilder) runScriptBuild(target *spec.Target, env Env
 	// Add build metadata
 	environVars = append(environVars, GetBuildMetadataEnvironVars(
 		outputPath,
-		target.Repository.Metadata.Branch,
-		target.Repository.Metadata.Commit,
+		&target.Repository.Metadata,
 		context.Builder.Options.Tag,
 		context.Builder.Options.Time,
 	)...)
 	// Create the command
-	cmd := exec.Command("python", args...)
+	cmd := exec.Command(getPythonInterpreter(pythonSpec), args...)
 	cmd.Dir = sourcePath
 	cmd.Env = environVars
 	if context.Workspace.Verbose {
@@ -220,6 +252,10 @@ func (this *PythonSourceCodeBuilder) runNuitkaBuild(target *spec.Target, env Env
 	if err != nil {
 		return err
 	}
+	if nuitkaSpec.Type == PythonNuitkaBuildTypeLib {
+		// Build as library
+		return this.runNuitkaLibBuild(target, environ, sourcePath, outputPath, startBuildTime, context)
+	}
 	if nuitkaSpec.Output == "" {
 		return errors.New("Require nuitka output")
 	}
@@ -240,8 +276,7 @@ func (this *PythonSourceCodeBuilder) runNuitkaBuild(target *spec.Target, env Env
 	// Add build metadata
 	environVars = append(environVars, GetBuildMetadataEnvironVars(
 		outputPath,
-		target.Repository.Metadata.Branch,
-		target.Repository.Metadata.Commit,
+		&target.Repository.Metadata,
 		context.Builder.Options.Tag,
 		context.Builder.Options.Time,
 	)...)
@@ -271,11 +306,12 @@ func (this *PythonSourceCodeBuilder) runNuitkaBuild(target *spec.Target, env Env
 				script,
 				PythonCodeInsertPointLine,
 				fmt.Sprintf(
-					"\n# Openlight build tool generated metadata\nbuildBranch=\"%s\"\nbuildCommit=\"%s\"\nbuildTime=\"%s\"\nbuildTag=\"%s\"\n# -*- ------------------------------ -*-\n# \n",
+					"\n# Openlight build tool generated metadata\nbuildBranch=\"%s\"\nbuildCommit=\"%s\"\nbuildTime=\"%s\"\nbuildTag=\"%s\"\nbuildDirty=%s\n# -*- ------------------------------ -*-\n# \n",
 					target.Repository.Metadata.Branch,
 					target.Repository.Metadata.Commit,
 					context.Builder.Options.Time.Format(time.RFC3339),
 					context.Builder.Options.Tag,
+					formatPythonBool(target.Repository.Metadata.Dirty),
 				),
 				1,
 			)
@@ -293,11 +329,6 @@ func (this *PythonSourceCodeBuilder) runNuitkaBuild(target *spec.Target, env Env
 		}
 		// Add entry script to args
 		args = append(args, entryScript)
-	} else {
-		// Binary as library
-		args = append(args, "--module")
-		// Not implemented
-		return errors.New("Build as a python module via nuitka is not supported yet")
 	}
 	// Run the command
 	cmd := exec.Command("nuitka", args...)
@@ -349,6 +380,391 @@ func (this *PythonSourceCodeBuilder) runNuitkaBuild(target *spec.Target, env Env
 	return nil
 }
 
+// Run python nuitka lib build, each module will be compiled as an importable extension module
+func (this *PythonSourceCodeBuilder) runNuitkaLibBuild(target *spec.Target, environ *PythonEnvironment, sourcePath, outputPath string, startBuildTime time.Time, context *BuilderContext) error {
+	pythonSpec := target.Spec.Build.Python
+	nuitkaSpec := pythonSpec.Nuitka
+	if len(nuitkaSpec.Modules) == 0 {
+		return errors.New("No module defined to build as library")
+	}
+	logger := context.Workspace.Logger.GetLoggerWithHeader(PythonNuitkaLogHeader)
+	environVars := this.getEnvironVars(target, environ, outputPath, context, true)
+	for _, module := range nuitkaSpec.Modules {
+		// Find the module file or package directory
+		modulePath, isPackage, err := findPythonModule(sourcePath, module)
+		if err != nil {
+			return err
+		}
+		// Output the extension module under its parent package path, so it's imported by the full module name
+		parts := strings.Split(module, ".")
+		moduleOutputPath := filepath.Join(append([]string{outputPath}, parts[:len(parts)-1]...)...)
+		if err := copyPythonParentPackages(sourcePath, outputPath, parts[:len(parts)-1]); err != nil {
+			return err
+		}
+		args := []string{"--module", "--output-dir", moduleOutputPath}
+		if isPackage {
+			// Compile the whole package into the extension module
+			args = append(args, "--recurse-to", module)
+		}
+		if nuitkaSpec.Lib != nil {
+			args = append(args, nuitkaSpec.Lib.Args...)
+		}
+		args = append(args, modulePath)
+		cmd := exec.Command("nuitka", args...)
+		cmd.Dir = sourcePath
+		cmd.Env = environVars
+		if err := this.runCommand(cmd, context, logger); err != nil {
+			return errors.New(fmt.Sprintf("Failed to build module [%s], error: %s", module, err))
+		}
+	}
+	// Collect the extension modules in the output directory
+	options := artifact.NewDefaultCollectFileArtifactOptions()
+	options.Recursive = true
+	options.Includes = PythonNuitkaLibOutputRegularExp
+	art, err := artifact.CollectFileArtifact(getPythonArtifactName(pythonSpec), outputPath, options)
+	if err != nil {
+		return err
+	}
+	if art == nil {
+		return errors.New("No extension module built")
+	}
+	// Create the build result
+	this.addBuildResult(target, sourcePath, outputPath, startBuildTime, art, context)
+	// Done
+	return nil
+}
+
+// Copy the __init__.py of the parent packages into the output path, the namespace packages (without __init__.py) are skipped
+// Parameters:
+// 	sourcePath 		The source path
+// 	outputPath 		The output path
+// 	packages 		The parent package names, e.g. [foo bar] of module foo.bar.baz
+func copyPythonParentPackages(sourcePath, outputPath string, packages []string) error {
+	for i := range packages {
+		p := filepath.Join(append([]string{}, packages[:i+1]...)...)
+		if err := os.MkdirAll(filepath.Join(outputPath, p), os.ModePerm); err != nil {
+			return err
+		}
+		initFile := filepath.Join(sourcePath, p, "__init__.py")
+		if _, err := os.Stat(initFile); err != nil {
+			continue
+		}
+		if err := util.CopyFile(initFile, filepath.Join(outputPath, p, "__init__.py")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Find the python module in path
+// Returns:
+// 	A tuple (path, isPackage, error) which the path is relative to the given path
+func findPythonModule(path, module string) (string, bool, error) {
+	modulePath := filepath.Join(strings.Split(module, ".")...)
+	if info, err := os.Stat(filepath.Join(path, modulePath)); err == nil && info.IsDir() {
+		if _, err := os.Stat(filepath.Join(path, modulePath, "__init__.py")); err == nil {
+			return modulePath, true, nil
+		}
+	}
+	if _, err := os.Stat(filepath.Join(path, modulePath+".py")); err == nil {
+		return modulePath + ".py", false, nil
+	}
+	return "", false, errors.New(fmt.Sprintf("Python module [%s] not found in [%s]", module, path))
+}
+
+// Run python wheel build
+func (this *PythonSourceCodeBuilder) runWheelBuild(target *spec.Target, env Environment, context *BuilderContext) error {
+	startBuildTime := time.Now()
+	pythonSpec := target.Spec.Build.Python
+	if pythonSpec == nil {
+		return errors.New("Python build spec not defined")
+	}
+	// Get the environment
+	environ := env.(*PythonEnvironment)
+	if environ == nil {
+		return errors.New("Invalid environment")
+	}
+	logger := context.Workspace.Logger.GetLoggerWithHeader(PythonWheelLogHeader)
+	// The source path
+	sourcePath := env.GetTargetPath(target)
+	if sourcePath == "" {
+		return errors.New("Source path not found")
+	}
+	// The output path
+	outputPath, err := context.Builder.EnsureTargetOutputPath(target)
+	if err != nil {
+		return err
+	}
+	// Create pip wheel command, only build the wheel of this target
+	args := []string{"-m", "pip", "wheel", "--no-deps", "--use-pep517", "-w", outputPath}
+	if pythonSpec.Wheel != nil {
+		args = append(args, pythonSpec.Wheel.Args...)
+	}
+	args = append(args, ".")
+	cmd := exec.Command(getPythonInterpreter(pythonSpec), args...)
+	cmd.Dir = sourcePath
+	cmd.Env = this.getEnvironVars(target, environ, outputPath, context, true)
+	if err := this.runCommand(cmd, context, logger); err != nil {
+		return err
+	}
+	// Collect the artifacts in the output directory
+	art, err := artifact.CollectFileArtifact(getPythonArtifactName(pythonSpec), outputPath, artifact.NewDefaultCollectFileArtifactOptions())
+	if err != nil {
+		return err
+	}
+	if art == nil {
+		return errors.New("No wheel built")
+	}
+	// Create the build result
+	this.addBuildResult(target, sourcePath, outputPath, startBuildTime, art, context)
+	// Done
+	return nil
+}
+
+// Run python virtualenv build
+func (this *PythonSourceCodeBuilder) runVenvBuild(target *spec.Target, env Environment, context *BuilderContext) error {
+	startBuildTime := time.Now()
+	pythonSpec := target.Spec.Build.Python
+	if pythonSpec == nil {
+		return errors.New("Python build spec not defined")
+	}
+	venvSpec := pythonSpec.Venv
+	if venvSpec == nil {
+		venvSpec = &spec.PythonVenvBuildSpec{}
+	}
+	// Get the environment
+	environ := env.(*PythonEnvironment)
+	if environ == nil {
+		return errors.New("Invalid environment")
+	}
+	logger := context.Workspace.Logger.GetLoggerWithHeader(PythonVenvLogHeader)
+	// The source path
+	sourcePath := env.GetTargetPath(target)
+	if sourcePath == "" {
+		return errors.New("Source path not found")
+	}
+	// The output path
+	outputPath, err := context.Builder.EnsureTargetOutputPath(target)
+	if err != nil {
+		return err
+	}
+	// Get the python targets to install, the dependencies are installed before the target itself
+	var installTargets []*spec.Target
+	err = context.Graph.Traverse(
+		target,
+		func(t *spec.Target, from *spec.Target, by *spec.TargetDependencySpec, ctx interface{}) error {
+			if t.Spec.Build.Type == BuilderTypePython {
+				for _, installTarget := range installTargets {
+					if installTarget.Key() == t.Key() {
+						return nil
+					}
+				}
+				installTargets = append(installTargets, t)
+			}
+			return nil
+		},
+		nil,
+		nil,
+		false,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+	// Create the virtualenv
+	venvPath := environ.GetVenvPath(target)
+	if err := os.RemoveAll(venvPath); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(venvPath), os.ModePerm); err != nil {
+		return err
+	}
+	environVars := this.getEnvironVars(target, environ, outputPath, context, false)
+	cmd := exec.Command(getPythonInterpreter(pythonSpec), "-m", "venv", "--copies", venvPath)
+	cmd.Env = environVars
+	if err := this.runCommand(cmd, context, logger); err != nil {
+		return errors.New(fmt.Sprintf("Failed to create virtualenv, error: %s", err))
+	}
+	venvPython := filepath.Join(venvPath, "bin", "python")
+	// Install the targets
+	for _, installTarget := range installTargets {
+		installPath := env.GetTargetPath(installTarget)
+		if installPath == "" {
+			return errors.New(fmt.Sprintf("Source path of target [%s] not found", installTarget.Key()))
+		}
+		// Install the requirements
+		requirementsFile := DefaultPythonRequirementsFile
+		if installTarget == target && venvSpec.Requirements != "" {
+			requirementsFile = venvSpec.Requirements
+		}
+		requirementsFile = filepath.Join(installPath, requirementsFile)
+		if _, err := os.Stat(requirementsFile); err == nil {
+			args := append([]string{"-m", "pip", "install", "-r", requirementsFile}, venvSpec.Args...)
+			cmd := exec.Command(venvPython, args...)
+			cmd.Dir = installPath
+			cmd.Env = environVars
+			if err := this.runCommand(cmd, context, logger); err != nil {
+				return errors.New(fmt.Sprintf("Failed to install requirements of target [%s], error: %s", installTarget.Key(), err))
+			}
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		// Install the target, the dependencies of the target are expected in requirements or dependency targets
+		args := append([]string{"-m", "pip", "install", "--no-deps"}, venvSpec.Args...)
+		args = append(args, ".")
+		cmd := exec.Command(venvPython, args...)
+		cmd.Dir = installPath
+		cmd.Env = environVars
+		if err := this.runCommand(cmd, context, logger); err != nil {
+			return errors.New(fmt.Sprintf("Failed to install target [%s], error: %s", installTarget.Key(), err))
+		}
+	}
+	// Make the scripts relocatable
+	if err := relocateVenvScripts(venvPath); err != nil {
+		return errors.New(fmt.Sprintf("Failed to relocate virtualenv scripts, error: %s", err))
+	}
+	// Package the virtualenv
+	artifactName := getPythonArtifactName(pythonSpec)
+	output := venvSpec.Output
+	if output == "" {
+		output = fmt.Sprintf("%s.tar.gz", artifactName)
+	}
+	pkg := filepath.Join(outputPath, output)
+	files, err := this.packageVenv(venvPath, pkg)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Failed to package virtualenv, error: %s", err))
+	}
+	// Create the build result
+	this.addBuildResult(target, sourcePath, outputPath, startBuildTime, artifact.NewFileArtifact(artifactName, pkg, files, true), context)
+	// Done
+	return nil
+}
+
+// Rewrite the absolute python shebangs of the scripts in virtualenv to the relative ones
+func relocateVenvScripts(venvPath string) error {
+	binPath := filepath.Join(venvPath, "bin")
+	infos, err := ioutil.ReadDir(binPath)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		filename := filepath.Join(binPath, info.Name())
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		if !bytes.HasPrefix(data, []byte("#!")) {
+			continue
+		}
+		index := bytes.IndexByte(data, '\n')
+		if index == -1 {
+			continue
+		}
+		// Only rewrite the shebang which points to the interpreter in the virtualenv
+		interpreter := strings.TrimSpace(string(data[2:index]))
+		if filepath.Dir(interpreter) != binPath || !strings.HasPrefix(filepath.Base(interpreter), "python") {
+			continue
+		}
+		shebang := fmt.Sprintf(PythonVenvRelocatableShebang, filepath.Base(interpreter))
+		if err := ioutil.WriteFile(filename, append([]byte(shebang), data[index+1:]...), info.Mode()); err != nil {
+			return err
+		}
+	}
+	// Done
+	return nil
+}
+
+// Package the virtualenv as a tar.gz file
+func (this *PythonSourceCodeBuilder) packageVenv(venvPath, pkg string) ([]string, error) {
+	pkgFile, err := os.Create(pkg)
+	if err != nil {
+		return nil, err
+	}
+	defer pkgFile.Close()
+	gzipWriter := gzip.NewWriter(pkgFile)
+	tarWriter := tar.NewWriter(gzipWriter)
+	files, err := util.TarWriteDir(venvPath, "", tarWriter)
+	if err != nil {
+		return nil, err
+	}
+	if err := tarWriter.Close(); err != nil {
+		return nil, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, err
+	}
+	return files, pkgFile.Close()
+}
+
+// Get the environment variables to run python commands
+// Parameters:
+// 	withPythonPath 	Add the module paths of the environment as PYTHONPATH or not
+func (this *PythonSourceCodeBuilder) getEnvironVars(target *spec.Target, environ *PythonEnvironment, outputPath string, context *BuilderContext, withPythonPath bool) []string {
+	var environVars []string
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(strings.ToLower(e), "pythonpath=") {
+			environVars = append(environVars, e)
+		}
+	}
+	// Add PYTHONPATH
+	if withPythonPath {
+		environVars = append(environVars, fmt.Sprintf("PYTHONPATH=%s", environ.GetPythonPathVar()))
+	}
+	// Add build metadata
+	return append(environVars, GetBuildMetadataEnvironVars(
+		outputPath,
+		&target.Repository.Metadata,
+		context.Builder.Options.Tag,
+		context.Builder.Options.Time,
+	)...)
+}
+
+// Run the command
+func (this *PythonSourceCodeBuilder) runCommand(cmd *exec.Cmd, context *BuilderContext, logger log.Logger) error {
+	if context.Workspace.Verbose {
+		// Connect stdout and stderr
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	} else {
+		// Ignore the stderr and stdout
+		cmd.Stdout = nil
+		cmd.Stderr = nil
+	}
+	logger.LeveledPrintf(log.LevelDebug, "Run command: %s\n", strings.Join(cmd.Args, " "))
+	return cmd.Run()
+}
+
+// Create and add the build result
+func (this *PythonSourceCodeBuilder) addBuildResult(target *spec.Target, sourcePath, outputPath string, startBuildTime time.Time, art artifact.Artifact, context *BuilderContext) {
+	buildResult := spec.NewBuildResult(target, context.Builder.NewBuildMetadata(target))
+	buildResult.Metadata.Builder = BuilderTypePython
+	buildResult.Metadata.BuildTimeUsage = time.Now().Sub(startBuildTime).Seconds()
+	buildResult.Metadata.LinkedPath = sourcePath
+	buildResult.Metadata.OutputPath = outputPath
+	buildResult.Artifacts[art.GetName()] = art
+	context.Builder.SetBuildResultDependency(target, buildResult)
+	context.Builder.AddResult(target, buildResult)
+}
+
+// Get the python interpreter of the spec
+func getPythonInterpreter(pythonSpec *spec.PythonBuildSpec) string {
+	if pythonSpec.Interpreter != "" {
+		return pythonSpec.Interpreter
+	}
+	return DefaultPythonInterpreter
+}
+
+// Get the artifact name of the spec
+func getPythonArtifactName(pythonSpec *spec.PythonBuildSpec) string {
+	if pythonSpec.Name != "" {
+		return pythonSpec.Name
+	}
+	return BuilderDefaultArtifactName
+}
+
 type PythonEnvironment struct {
 	path    string
 	targets map[string]*PythonTargetEnvironment
@@ -398,6 +814,11 @@ func (this *PythonEnvironment) GetEnvironVars() map[string]string {
 	return vars
 }
 
+// Get the virtualenv path of the target
+func (this *PythonEnvironment) GetVenvPath(target *spec.Target) string {
+	return filepath.Join(this.path, PythonVenvDirName, GetTargetRegularKey(target))
+}
+
 func (this *PythonEnvironment) GetPythonPathVar() string {
 	var paths []string
 	for _, environ := range this.targets {
@@ -433,3 +854,11 @@ func (this *PythonEnvironment) EnsurePackagePath(target *spec.Target) (string, e
 	// Done
 	return environ.Path, nil
 }
+
+// Format the bool as python literal
+func formatPythonBool(b bool) string {
+	if b {
+		return "True"
+	}
+	return "False"
+}
@@ -24,7 +24,7 @@ import (
 const (
 	ShellLogHeader = "Shell"
 
-	BuilderTypeShell = "shell"
+	BuilderTypeShell = spec.BuildTypeShell
 )
 
 type ShellSourceCodeBuilder struct{}
@@ -85,8 +85,7 @@ func (this *ShellSourceCodeBuilder) Build(target *spec.Target, env Environment,
 	// Get the environment variables
 	environVars := GetBuildMetadataEnvironVars(
 		outputPath,
-		target.Repository.Metadata.Branch,
-		target.Repository.Metadata.Commit,
+		&target.Repository.Metadata,
 		context.Builder.Options.Tag,
 		context.Builder.Options.Time,
 	)
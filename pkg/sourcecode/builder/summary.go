@@ -0,0 +1,129 @@
+// Author: lipixun
+// Created Time : 六 12/31 11:20:37 2016
+//
+// File Name: summary.go
+// Description:
+//	The timing summary of the build
+//	The critical path is the dependency chain (only the dependencies marked as build) with the longest prepare and build time,
+//	the cached targets (built by the previous build of another target) are counted as zero since they are not built this time
+package builder
+
+import (
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"sort"
+	"time"
+)
+
+type targetTiming struct {
+	builtBy string // The key of the target whose build built this target
+	prepare time.Duration
+	build   time.Duration
+	copy    time.Duration
+}
+
+type criticalPath struct {
+	keys      []string
+	timeUsage time.Duration
+}
+
+// Get the timing of the target, create a new one if not found
+func (this *Builder) getTiming(target *spec.Target) *targetTiming {
+	timing := this.timings[target.Key()]
+	if timing == nil {
+		timing = &targetTiming{}
+		this.timings[target.Key()] = timing
+	}
+	return timing
+}
+
+// Get the timing summary of the built targets and their dependencies
+func (this *Builder) GetBuildSummary(targets ...*spec.Target) (*spec.BuildSummary, error) {
+	roots := make(map[string]bool)
+	for _, target := range targets {
+		roots[target.Key()] = true
+	}
+	var summary spec.BuildSummary
+	var longest *criticalPath
+	visited := make(map[string]bool)
+	paths := make(map[string]*criticalPath)
+	for _, target := range targets {
+		// Collect the timings in build order
+		err := this.graph.Traverse(
+			target,
+			func(target *spec.Target, from *spec.Target, by *spec.TargetDependencySpec, context interface{}) error {
+				if visited[target.Key()] {
+					return nil
+				}
+				visited[target.Key()] = true
+				timing := this.getTiming(target)
+				cached := !roots[timing.builtBy]
+				summary.Targets = append(summary.Targets, &spec.TargetBuildTiming{
+					Target:           target.Key(),
+					PrepareTimeUsage: timing.prepare.Seconds(),
+					BuildTimeUsage:   timing.build.Seconds(),
+					CopyTimeUsage:    timing.copy.Seconds(),
+					Cached:           cached,
+				})
+				if !cached {
+					summary.TotalTimeUsage += (timing.prepare + timing.build + timing.copy).Seconds()
+				}
+				return nil
+			},
+			this.buildGraphTraverseController,
+			nil,
+			false,
+			nil,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if path := this.getCriticalPath(target, roots, paths); longest == nil || path.timeUsage > longest.timeUsage {
+			longest = path
+		}
+	}
+	if longest != nil {
+		summary.CriticalPath = longest.keys
+		summary.CriticalPathTimeUsage = longest.timeUsage.Seconds()
+	}
+	// Done
+	return &summary, nil
+}
+
+// Get the critical path to the target
+// Parameters:
+// 	target 		The target
+// 	roots 		The keys of the summarized targets, the targets built by the others are cached
+// 	paths 		The calculated critical paths, key is target key
+func (this *Builder) getCriticalPath(target *spec.Target, roots map[string]bool, paths map[string]*criticalPath) *criticalPath {
+	if path := paths[target.Key()]; path != nil {
+		return path
+	}
+	// Get the longest path of the dependencies, the dependencies are sorted by name to make the result stable
+	var names []string
+	for name := range target.Spec.Deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var longest *criticalPath
+	for _, name := range names {
+		dep := target.Spec.Deps[name]
+		depTarget := this.graph.Targets[dep.Key()]
+		if depTarget == nil || !this.buildGraphTraverseController(dep, target, depTarget, nil) {
+			continue
+		}
+		if path := this.getCriticalPath(depTarget, roots, paths); longest == nil || path.timeUsage > longest.timeUsage {
+			longest = path
+		}
+	}
+	path := &criticalPath{keys: []string{target.Key()}}
+	if longest != nil {
+		path.keys = append(append([]string{}, longest.keys...), target.Key())
+		path.timeUsage = longest.timeUsage
+	}
+	if timing := this.timings[target.Key()]; timing != nil && roots[timing.builtBy] {
+		path.timeUsage += timing.prepare + timing.build
+	}
+	paths[target.Key()] = path
+	// Done
+	return path
+}
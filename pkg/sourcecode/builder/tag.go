@@ -4,11 +4,47 @@
 // File Name: tag.go
 // Description:
 //
+// 	The build tag strategies
+// 		random 		8 random hex bytes
+// 		commit 		The short hash of the commit
+// 		describe 	The commit described by tags (git describe --tags --always)
+// 		timestamp 	The build time in UTC, formatted as 20060102150405
+// 		template 	A go template combining the values above, e.g. {{.Describe}}-{{.Timestamp}}
+//
+// 	A "-dirty" suffix will be added when the working tree has uncommitted changes (except random strategy)
+// 	All chars except letters, digits, underscore, dot and dash will be replaced by dash since the tag is used as docker image tag
+//
 package builder
 
 import (
+	"bytes"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"regexp"
+	"text/template"
+	"time"
+)
+
+const (
+	TagStrategyRandom    = "random"
+	TagStrategyCommit    = "commit"
+	TagStrategyDescribe  = "describe"
+	TagStrategyTimestamp = "timestamp"
+	TagStrategyTemplate  = "template"
+
+	DefaultTagStrategy = TagStrategyRandom
+
+	TagDirtySuffix     = "-dirty"
+	TagShortCommitSize = 7
+	TagTimestampFormat = "20060102150405"
+	TagMaxLength       = 128
+)
+
+var (
+	TagInvalidCharRegularExp = regexp.MustCompile("[^a-zA-Z0-9_\\.-]")
 )
 
 func NewTag() (string, error) {
@@ -18,3 +54,85 @@ func NewTag() (string, error) {
 	}
 	return hex.EncodeToString(rands), nil
 }
+
+// The values used by tag template
+type TagRecipient struct {
+	Random    string // The random tag
+	Commit    string // The short hash of the commit
+	Describe  string // The commit described by tags
+	Timestamp string // The build time
+	Branch    string // The branch
+}
+
+// Create a new tag by strategy
+// Parameters:
+// 	strategy 		The tag strategy
+// 	tagTemplate 	The go template of the tag, only used by template strategy
+// 	repository 		The repository which the tag is derived from
+// 	t 				The build time
+func NewTagByStrategy(strategy, tagTemplate string, repository *spec.Repository, t time.Time) (string, error) {
+	if strategy == "" {
+		strategy = DefaultTagStrategy
+	}
+	if strategy == TagStrategyRandom {
+		return NewTag()
+	}
+	if repository == nil {
+		return "", errors.New(fmt.Sprintf("Require repository for tag strategy [%s]", strategy))
+	}
+	metadata := repository.Metadata
+	random, err := NewTag()
+	if err != nil {
+		return "", err
+	}
+	commit := metadata.Commit
+	if len(commit) > TagShortCommitSize {
+		commit = commit[:TagShortCommitSize]
+	}
+	recipient := TagRecipient{
+		Random:    random,
+		Commit:    commit,
+		Describe:  metadata.Describe,
+		Timestamp: t.UTC().Format(TagTimestampFormat),
+		Branch:    metadata.Branch,
+	}
+	// Generate the tag
+	var tag string
+	switch strategy {
+	case TagStrategyCommit:
+		tag = recipient.Commit
+	case TagStrategyDescribe:
+		tag = recipient.Describe
+	case TagStrategyTimestamp:
+		tag = recipient.Timestamp
+	case TagStrategyTemplate:
+		if tagTemplate == "" {
+			return "", errors.New("Require tag template")
+		}
+		temp, err := template.New("tag").Parse(tagTemplate)
+		if err != nil {
+			return "", errors.New(fmt.Sprintf("Failed to parse tag template, error: %s", err))
+		}
+		buf := new(bytes.Buffer)
+		if err := temp.Execute(buf, recipient); err != nil {
+			return "", errors.New(fmt.Sprintf("Failed to execute tag template, error: %s", err))
+		}
+		tag = buf.String()
+	default:
+		return "", errors.New(fmt.Sprintf("Unknown tag strategy [%s]", strategy))
+	}
+	if tag == "" {
+		return "", errors.New(fmt.Sprintf("Empty tag generated by strategy [%s]", strategy))
+	}
+	if metadata.Dirty {
+		tag += TagDirtySuffix
+	}
+	// Replace the invalid chars
+	tag = TagInvalidCharRegularExp.ReplaceAllString(tag, "-")
+	if len(tag) > TagMaxLength {
+		return "", errors.New(fmt.Sprintf("Tag [%s] is too long, the max length is %d", tag, TagMaxLength))
+	}
+	// Done
+	return tag, nil
+}
+
@@ -0,0 +1,72 @@
+// Author: lipixun
+// Created Time : 四 12/29 18:12:30 2016
+//
+// File Name: tag_test.go
+// Description:
+//
+package builder
+
+import (
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestTagRepository(branch, commit, describe string, dirty bool) *spec.Repository {
+	return &spec.Repository{
+		Metadata: spec.RepositoryMetadata{
+			Branch:   branch,
+			Commit:   commit,
+			Describe: describe,
+			Dirty:    dirty,
+		},
+	}
+}
+
+func TestNewTagByStrategy(t *testing.T) {
+	buildTime := time.Date(2016, 12, 29, 18, 12, 30, 0, time.FixedZone("CST", 8*3600))
+	repository := newTestTagRepository("feature/tag", "1a2b3c4d5e6f", "v1.0-3-g1a2b3c4", false)
+	dirtyRepository := newTestTagRepository("master", "1a2b3c4d5e6f", "v1.0+build", true)
+	for _, c := range []struct {
+		Strategy   string
+		Template   string
+		Repository *spec.Repository
+		Expected   string
+		Valid      bool
+	}{
+		{TagStrategyCommit, "", repository, "1a2b3c4", true},
+		{TagStrategyDescribe, "", repository, "v1.0-3-g1a2b3c4", true},
+		{TagStrategyTimestamp, "", repository, "20161229101230", true},
+		{TagStrategyTemplate, "{{.Branch}}-{{.Commit}}", repository, "feature-tag-1a2b3c4", true},
+		{TagStrategyCommit, "", dirtyRepository, "1a2b3c4-dirty", true},
+		{TagStrategyDescribe, "", dirtyRepository, "v1.0-build-dirty", true},
+		{TagStrategyTemplate, "{{.Branch}} {{.Timestamp}}", dirtyRepository, "master-20161229101230-dirty", true},
+		{TagStrategyTemplate, "", repository, "", false},
+		{TagStrategyTemplate, "{{.Unknown}}", repository, "", false},
+		{TagStrategyTemplate, strings.Repeat("a", TagMaxLength+1), repository, "", false},
+		{TagStrategyDescribe, "", newTestTagRepository("master", "1a2b3c4", "", false), "", false},
+		{TagStrategyCommit, "", nil, "", false},
+		{"unknown", "", repository, "", false},
+	} {
+		tag, err := NewTagByStrategy(c.Strategy, c.Template, c.Repository, buildTime)
+		if c.Valid && err != nil {
+			t.Errorf("Failed to create tag by strategy [%s] template [%s], error: %s", c.Strategy, c.Template, err)
+		} else if !c.Valid && err == nil {
+			t.Errorf("Expect error when creating tag by strategy [%s] template [%s]", c.Strategy, c.Template)
+		} else if tag != c.Expected {
+			t.Errorf("Mismatch tag of strategy [%s] template [%s]. Expected [%s] Actually [%s]", c.Strategy, c.Template, c.Expected, tag)
+		}
+	}
+	// The random tag
+	for _, strategy := range []string{"", TagStrategyRandom} {
+		tag, err := NewTagByStrategy(strategy, "", nil, buildTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tag) != 16 || TagInvalidCharRegularExp.MatchString(tag) {
+			t.Errorf("Invalid random tag [%s]", tag)
+		}
+	}
+}
+
@@ -0,0 +1,111 @@
+// Author: lipixun
+// Created Time : 三 12/28 17:32:05 2016
+//
+// File Name: affected.go
+// Description:
+//	Find the targets affected by the changed files
+package graph
+
+import (
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"path/filepath"
+	"strings"
+)
+
+// Get the targets affected by the changed files of the repository
+// The targets whose inputs (the target path, the linked source paths, the dockerfile and the local docker files) contain any changed file
+// are affected directly, and all targets depending on an affected target (transitively) are affected as well.
+// The change of the spec file (including the included spec files) affects all targets of the repository.
+// The changed submodule (reported as the submodule path by git) affects the targets whose inputs are inside the submodule.
+// Parameters:
+// 	repo 			The repository, its targets should be loaded
+// 	files 			The changed file paths relative to the repository root
+// Returns:
+// 	The affected targets sorted by key
+func (this *Graph) GetAffectedTargets(repo *spec.Repository, files []string) []*spec.Target {
+	// Get the submodule paths relative to the repository root
+	submodules := make(map[string]bool)
+	for _, submodule := range repo.Local.Submodules {
+		if p, err := filepath.Rel(repo.Local.Path, submodule.Path); err == nil {
+			submodules[filepath.ToSlash(p)] = true
+		}
+	}
+	affected := make(map[string]*spec.Target)
+	for _, target := range this.Targets {
+		if target.Repository.Uri != repo.Uri {
+			continue
+		}
+		for _, file := range files {
+			if isTargetInputFile(target, file, submodules) {
+				// Affect the target and its dependents
+				affected[target.Key()] = target
+				for _, dependent := range this.GetDependents(target, true) {
+					affected[dependent.Key()] = dependent
+				}
+				break
+			}
+		}
+	}
+	// Done
+	return sortTargets(affected)
+}
+
+// Check if the file (relative to the repository root) is an input of the target
+// Parameters:
+// 	target 		The target
+// 	file 		The changed file
+// 	submodules 	The submodule paths (relative to the repository root, in slash form)
+func isTargetInputFile(target *spec.Target, file string, submodules map[string]bool) bool {
+	file = filepath.ToSlash(filepath.Clean(file))
+	if file == spec.SpecFileName || file == spec.LockFileName {
+		return true
+	}
+	if target.Repository != nil && target.Repository.Spec != nil {
+		for _, include := range target.Repository.Spec.Include {
+			if file == include {
+				return true
+			}
+		}
+	}
+	for _, path := range getTargetInputPaths(target) {
+		path = filepath.ToSlash(filepath.Clean(path))
+		if path == "." || path == file || strings.HasPrefix(file, path+"/") {
+			return true
+		}
+		if submodules[file] && strings.HasPrefix(path, file+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the input paths (relative to the repository root) of the target
+func getTargetInputPaths(target *spec.Target) []string {
+	paths := []string{target.Spec.Path}
+	var links []spec.SourceCodeLink
+	if target.Spec.Build.Golang != nil {
+		links = append(links, target.Spec.Build.Golang.Links...)
+	}
+	if target.Spec.Build.Python != nil {
+		links = append(links, target.Spec.Build.Python.Links...)
+	}
+	if target.Spec.Build.Shell != nil {
+		links = append(links, target.Spec.Build.Shell.Links...)
+	}
+	for _, link := range links {
+		// The link path is relative to the target path
+		paths = append(paths, filepath.Join(target.Spec.Path, link.Path))
+	}
+	if dockerSpec := target.Spec.Build.Docker; dockerSpec != nil {
+		// The dockerfile and the local files are relative to the target path, and may be out of it (e.g. ../common)
+		if dockerSpec.Dockerfile != "" {
+			paths = append(paths, filepath.Join(target.Spec.Path, dockerSpec.Dockerfile))
+		}
+		for _, f := range dockerSpec.Files {
+			if f.Source.Local != nil {
+				paths = append(paths, filepath.Join(target.Spec.Path, f.Source.Local.Path))
+			}
+		}
+	}
+	return paths
+}
@@ -0,0 +1,63 @@
+// Author: lipixun
+// Created Time : 四 12/29 19:05:16 2016
+//
+// File Name: affected_test.go
+// Description:
+//
+package graph
+
+import (
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"gopkg.in/yaml.v2"
+	"testing"
+)
+
+const testAffectedTargetSpec = `path: services/api
+build:
+  type: docker
+  docker:
+    dockerfile: ../docker/api.Dockerfile
+    files:
+      - target: common
+        source:
+          local:
+            path: ../../common
+      - target: bin
+        source:
+          dep:
+            name: bin
+            artifact: default
+`
+
+func TestIsTargetInputFile(t *testing.T) {
+	var targetSpec spec.TargetSpec
+	if err := yaml.Unmarshal([]byte(testAffectedTargetSpec), &targetSpec); err != nil {
+		t.Fatal(err)
+	}
+	repository := &spec.Repository{Uri: "github.com/ops-openlight/test", Spec: &spec.RepositorySpec{Include: []string{"specs/services.yaml"}}}
+	target := &spec.Target{Name: "api", Repository: repository, Spec: &targetSpec}
+	submodules := map[string]bool{"vendor/lib": true, "common": true}
+	for _, c := range []struct {
+		File     string
+		Expected bool
+	}{
+		{"services/api/main.go", true},
+		{"services/api", true},
+		{"services/docker/api.Dockerfile", true},
+		{"services/docker/web.Dockerfile", false},
+		{"common/config.yaml", true},
+		{"common", true},
+		{"services/apiserver/main.go", false},
+		{"services/web/main.go", false},
+		{"vendor/lib", false},
+		{spec.SpecFileName, true},
+		{spec.LockFileName, true},
+		{"specs/services.yaml", true},
+		{"./specs/services.yaml", true},
+		{"specs/other.yaml", false},
+	} {
+		if actual := isTargetInputFile(target, c.File, submodules); actual != c.Expected {
+			t.Errorf("Mismatch input file [%s]. Expected [%v] Actually [%v]", c.File, c.Expected, actual)
+		}
+	}
+}
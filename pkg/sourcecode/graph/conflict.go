@@ -0,0 +1,171 @@
+// Author: lipixun
+// Created Time : 二 12/27 16:40:22 2016
+//
+// File Name: conflict.go
+// Description:
+//	Detect and resolve the conflicting requests of the same repository
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ops-openlight/openlight/pkg/log"
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"github.com/ops-openlight/openlight/pkg/uri"
+	"sort"
+)
+
+const (
+	ConflictPolicyFail      = "fail"       // Fail the loading
+	ConflictPolicyFirstWins = "first-wins" // Use the first request
+	ConflictPolicyRootWins  = "root-wins"  // Use the reference of the root repository
+	ConflictPolicyOverride  = "override"   // Use the overrides of the root repository spec
+
+	DefaultConflictPolicy = ConflictPolicyFail
+)
+
+// Check if the conflict policy is valid
+func IsValidConflictPolicy(policy string) bool {
+	return policy == ConflictPolicyFail || policy == ConflictPolicyFirstWins || policy == ConflictPolicyRootWins || policy == ConflictPolicyOverride
+}
+
+// A request of repository from the reference of a repository
+type RepositoryRequest struct {
+	Uri    string `json:"uri"`
+	Branch string `json:"branch,omitempty"`
+	Commit string `json:"commit,omitempty"`
+	Tag    string `json:"tag,omitempty"`
+	By     string `json:"by"` // The uri of the requesting repository
+}
+
+func (this *RepositoryRequest) String() string {
+	return fmt.Sprintf("%s requested by %s", (&uri.RepositoryUri{Uri: this.Uri, Branch: this.Branch, Commit: this.Commit, Tag: this.Tag}).String(), this.By)
+}
+
+// The conflicting requests of a repository
+type RepositoryConflict struct {
+	Uri      string               `json:"uri"`
+	Requests []*RepositoryRequest `json:"requests"`
+	Policy   string               `json:"policy"`
+	Resolved string               `json:"resolved"` // The used repository uri with ref, empty means the conflict is not resolved
+}
+
+// Get the conflict report of all conflicting repositories, sorted by uri
+func (this *Graph) GetConflictReport() []*RepositoryConflict {
+	var conflicts []*RepositoryConflict
+	for _, conflict := range this.Conflicts {
+		conflict.Requests = this.Requests[conflict.Uri]
+		conflicts = append(conflicts, conflict)
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Uri < conflicts[j].Uri
+	})
+	return conflicts
+}
+
+// Get the conflict policy
+func (this *Graph) getConflictPolicy() string {
+	if this.Options.ConflictPolicy == "" {
+		return DefaultConflictPolicy
+	}
+	return this.Options.ConflictPolicy
+}
+
+// Request the repository from the reference of repository r
+// Returns:
+// 	The effective reference to load the repository
+func (this *Graph) requestRepository(repository string, r *spec.Repository) (*spec.RepositoryReferenceSpec, error) {
+	refer, ok := r.Spec.References[repository]
+	if !ok {
+		this.logger.LeveledPrintf(log.LevelError, "Repository reference of [%s] not found in repository [%s]\n", repository, r.Uri)
+		return nil, errors.New("Repository reference not found")
+	}
+	// Record the request
+	found := false
+	for _, request := range this.Requests[repository] {
+		if request.By == r.Uri {
+			found = true
+			break
+		}
+	}
+	if !found {
+		this.Requests[repository] = append(this.Requests[repository], &RepositoryRequest{Uri: repository, Branch: refer.Branch, Commit: refer.Commit, Tag: refer.Tag, By: r.Uri})
+	}
+	// Get the pinned reference by policy
+	policy := this.getConflictPolicy()
+	if !IsValidConflictPolicy(policy) {
+		return nil, errors.New(fmt.Sprintf("Unknown conflict policy [%s]", policy))
+	}
+	pinned, err := this.getPinnedReference(repository, refer, policy)
+	if err != nil {
+		return nil, err
+	}
+	effective, ok := this.References[repository]
+	if !ok {
+		// The first request
+		effective = refer
+		if pinned != nil {
+			effective = pinned
+		}
+		this.References[repository] = effective
+	}
+	if isSameRepositoryRef(refer, effective) {
+		return effective, nil
+	}
+	// Conflict found
+	conflict, ok := this.Conflicts[repository]
+	if !ok {
+		conflict = &RepositoryConflict{Uri: repository, Policy: policy}
+		this.Conflicts[repository] = conflict
+	}
+	if pinned != nil || policy == ConflictPolicyFirstWins {
+		conflict.Resolved = (&uri.RepositoryUri{Uri: repository, Branch: effective.Branch, Commit: effective.Commit, Tag: effective.Tag}).String()
+		this.logger.LeveledPrintf(log.LevelWarn, "Conflicting requests of repository [%s], use [%s] by policy [%s]\n", repository, conflict.Resolved, policy)
+		return effective, nil
+	}
+	conflict.Resolved = ""
+	this.logger.LeveledPrintf(log.LevelError, "Conflicting requests of repository [%s] by policy [%s]:\n", repository, policy)
+	for _, request := range this.Requests[repository] {
+		this.logger.LeveledPrintf(log.LevelError, "\t%s\n", request.String())
+	}
+	return nil, errors.New(fmt.Sprintf("Conflicting requests of repository [%s]", repository))
+}
+
+// Get the reference pinned by the root repositories, returns nil if not pinned
+func (this *Graph) getPinnedReference(repository string, refer *spec.RepositoryReferenceSpec, policy string) (*spec.RepositoryReferenceSpec, error) {
+	var pinned *spec.RepositoryReferenceSpec
+	for _, root := range this.roots {
+		var rootRefer *spec.RepositoryReferenceSpec
+		switch policy {
+		case ConflictPolicyRootWins:
+			rootRefer = root.Spec.References[repository]
+		case ConflictPolicyOverride:
+			if override, ok := root.Spec.Overrides[repository]; ok && override != nil {
+				overrideRefer := *refer
+				overrideRefer.Branch, overrideRefer.Commit, overrideRefer.Tag = override.Branch, override.Commit, override.Tag
+				rootRefer = &overrideRefer
+			}
+		}
+		if rootRefer == nil {
+			continue
+		}
+		if pinned != nil && !isSameRepositoryRef(pinned, rootRefer) {
+			return nil, errors.New(fmt.Sprintf("Repository [%s] is pinned differently by root repositories", repository))
+		}
+		pinned = rootRefer
+	}
+	return pinned, nil
+}
+
+// Check if the two references use the same ref, the reference without any ref uses the default branch
+func isSameRepositoryRef(a, b *spec.RepositoryReferenceSpec) bool {
+	return getRepositoryRefBranch(a) == getRepositoryRefBranch(b) && a.Commit == b.Commit && a.Tag == b.Tag
+}
+
+// Get the branch of the reference, returns the default branch if no ref specified
+func getRepositoryRefBranch(refer *spec.RepositoryReferenceSpec) string {
+	if refer.Branch == "" && refer.Commit == "" && refer.Tag == "" {
+		return RepositoryDefaultBranch
+	}
+	return refer.Branch
+}
@@ -0,0 +1,102 @@
+// Author: lipixun
+// Created Time : 四 12/29 21:10:37 2016
+//
+// File Name: conflict_test.go
+// Description:
+//
+package graph
+
+import (
+	"fmt"
+	"github.com/ops-openlight/openlight/pkg/sourcecode"
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"gopkg.in/yaml.v2"
+	"os"
+	"testing"
+)
+
+// The diamond: a:x -> (b:x, c:x) -> d:x, b and c reference d with the branches of the test case
+const testConflictRootSpec = `uri: github.com/test/a
+references:
+  github.com/test/b:
+    remote: /src/b
+  github.com/test/c:
+    remote: /src/c
+targets:
+  x:
+    deps:
+      b:
+        target: x
+        repository: github.com/test/b
+      c:
+        target: x
+        repository: github.com/test/c
+`
+
+const testConflictMiddleSpec = `uri: github.com/test/%s
+references:
+  github.com/test/d:
+    remote: /src/d
+    branch: %s
+targets:
+  x:
+    deps:
+      d:
+        target: x
+        repository: github.com/test/d
+`
+
+const testConflictLeafSpec = `uri: github.com/test/d
+targets:
+  x: {}
+`
+
+func newTestConflictRepository(t *testing.T, data string) *spec.Repository {
+	var repoSpec spec.RepositorySpec
+	if err := yaml.Unmarshal([]byte(data), &repoSpec); err != nil {
+		t.Fatal(err)
+	}
+	return &spec.Repository{Uri: repoSpec.Uri, Source: "/src/" + repoSpec.Uri[len("github.com/test/"):], Spec: &repoSpec}
+}
+
+func TestDiamondConflict(t *testing.T) {
+	for _, c := range []struct {
+		Policy   string
+		BranchB  string
+		BranchC  string
+		Conflict bool
+		Valid    bool
+	}{
+		{ConflictPolicyFail, "v1", "v2", true, false},
+		{ConflictPolicyFirstWins, "v1", "v2", true, true},
+		{ConflictPolicyFail, "v1", "v1", false, true},
+		{ConflictPolicyFail, "", "master", false, true},
+	} {
+		g, path := newTestGraph(t, GraphOptions{ConflictPolicy: c.Policy})
+		// The repositories are loaded already, so the graph is resolved without loaders
+		root := newTestConflictRepository(t, testConflictRootSpec)
+		for _, repo := range []*spec.Repository{
+			root,
+			newTestConflictRepository(t, fmt.Sprintf(testConflictMiddleSpec, "b", c.BranchB)),
+			newTestConflictRepository(t, fmt.Sprintf(testConflictMiddleSpec, "c", c.BranchC)),
+			newTestConflictRepository(t, testConflictLeafSpec),
+		} {
+			g.Repositories[repo.Uri] = repo
+		}
+		g.roots = append(g.roots, root)
+		err := g.resolve(root, nil, sourcecode.NewTracerWithRecorder(nil))
+		os.RemoveAll(path)
+		if c.Valid && err != nil {
+			t.Errorf("Failed to resolve diamond of branches [%s] [%s] by policy [%s], error: %s", c.BranchB, c.BranchC, c.Policy, err)
+		} else if !c.Valid && err == nil {
+			t.Errorf("Expect conflict error of branches [%s] [%s] by policy [%s]", c.BranchB, c.BranchC, c.Policy)
+		}
+		// Both requests should be recorded whichever is loaded first
+		if len(g.Requests["github.com/test/d"]) != 2 {
+			t.Errorf("Mismatch requests of branches [%s] [%s]. Expected [2] Actually %v", c.BranchB, c.BranchC, g.Requests["github.com/test/d"])
+		}
+		if _, ok := g.Conflicts["github.com/test/d"]; ok != c.Conflict {
+			t.Errorf("Mismatch conflict of branches [%s] [%s]. Expected [%v] Actually [%v]", c.BranchB, c.BranchC, c.Conflict, ok)
+		}
+	}
+}
@@ -11,11 +11,10 @@ import (
 	"fmt"
 	"github.com/ops-openlight/openlight/pkg/log"
 	"github.com/ops-openlight/openlight/pkg/sourcecode"
-	"github.com/ops-openlight/openlight/pkg/sourcecode/repofinder"
 	"github.com/ops-openlight/openlight/pkg/sourcecode/repoloader"
 	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"github.com/ops-openlight/openlight/pkg/uri"
 	"github.com/ops-openlight/openlight/pkg/workspace"
-	"strings"
 )
 
 const (
@@ -32,12 +31,22 @@ type Graph struct {
 	Options          GraphOptions
 	Repositories     map[string]*spec.Repository
 	Targets          map[string]*spec.Target
-	RemoteOverwrites map[string]string // Key is uri, value is remote
+	RemoteOverwrites map[string]string                        // Key is uri, value is remote
+	Resolutions      map[string]*RepositoryResolution         // Key is uri, value is where the loaded repository comes from
+	References       map[string]*spec.RepositoryReferenceSpec // Key is uri, value is the reference which the repository is loaded by
+	Requests         map[string][]*RepositoryRequest          // Key is uri, value is all requests of the repository
+	Conflicts        map[string]*RepositoryConflict           // Key is uri
+	roots            []*spec.Repository                       // The repositories loaded directly
 }
 
 type GraphOptions struct {
 	UseLocalDependency bool // Whether to use local repository to resolve the dependency
 	DisableFinder      bool
+	ResolutionPolicy   string                    // How to resolve the referenced repository, empty means DefaultResolutionPolicy
+	Lock               *spec.RepositoryLock      // Load the referenced repositories at the locked commits (fail if loaded at other commits), nil means no lock
+	ConflictPolicy     string                    // How to resolve the conflicting requests of a repository, empty means DefaultConflictPolicy
+	LFSStore           string                    // The local git LFS store used by all repositories, empty means the default store of each repository
+	TraceRecorder      *sourcecode.TraceRecorder // Record the loading spans, nil means no recording
 }
 
 func New(ws *workspace.Workspace, options GraphOptions) (*Graph, error) {
@@ -52,6 +61,10 @@ func New(ws *workspace.Workspace, options GraphOptions) (*Graph, error) {
 		Repositories:     make(map[string]*spec.Repository),
 		Targets:          make(map[string]*spec.Target),
 		RemoteOverwrites: make(map[string]string),
+		Resolutions:      make(map[string]*RepositoryResolution),
+		References:       make(map[string]*spec.RepositoryReferenceSpec),
+		Requests:         make(map[string][]*RepositoryRequest),
+		Conflicts:        make(map[string]*RepositoryConflict),
 	}, nil
 }
 
@@ -60,20 +73,27 @@ func (this *Graph) Workspace() *workspace.Workspace {
 }
 
 type LoadOptions struct {
-	Uri     string // The expected uri of the loading repository
-	Type    string
-	Branch  string
-	Commit  string
-	Targets []string
+	Uri        string // The expected uri of the loading repository
+	Type       string
+	Branch     string
+	Commit     string
+	Tag        string // The tag or semver range of tags
+	Subdir     string // The directory of the spec file relative to the repository root
+	Checksum   string // The checksum of the archive
+	Submodules bool   // Init and update the git submodules recursively
+	LFS        bool   // Fetch the git LFS objects
+	Targets    []string
+
+	resolution *RepositoryResolution // How the remote is resolved, nil means the repository is loaded directly
 }
 
 // Load a repository
 func (this *Graph) Load(remote string, options LoadOptions) (*spec.Repository, error) {
-	return this.load(remote, options, sourcecode.NewTracer())
+	return this.load(remote, options, sourcecode.NewTracerWithRecorder(this.Options.TraceRecorder))
 }
 
 // Load a repository
-func (this *Graph) load(remote string, options LoadOptions, tracer *sourcecode.Tracer) (*spec.Repository, error) {
+func (this *Graph) load(remote string, options LoadOptions, tracer *sourcecode.Tracer) (repo *spec.Repository, err error) {
 	this.logger.LeveledPrintf(log.LevelDebug, "Load repository: %s\n", remote)
 	// Push into tracer
 	if options.Uri != "" {
@@ -81,15 +101,24 @@ func (this *Graph) load(remote string, options LoadOptions, tracer *sourcecode.T
 	} else {
 		tracer.Push(sourcecode.TraceTypeRepository, "<root>", "<root>")
 	}
-	defer tracer.Pop()
+	defer func() {
+		tracer.PopWithError(err)
+	}()
 	this.logger.LeveledPrintf(log.LevelInfo, "Loading %s\n", tracer.String())
 	// Rewrite the remote
+	root := options.resolution == nil
+	resolution := options.resolution
+	if root {
+		resolution = &RepositoryResolution{Uri: options.Uri, Source: ResolutionSourceRoot}
+	}
 	if options.Uri != "" {
 		if _remote, ok := this.RemoteOverwrites[options.Uri]; ok {
 			this.logger.LeveledPrintf(log.LevelDebug, "Overwrite remote of repository [%s] from [%s] to [%s]\n", options.Uri, remote, _remote)
 			remote = _remote
+			resolution = &RepositoryResolution{Uri: options.Uri, Source: ResolutionSourceOverwrite}
 		}
 	}
+	resolution.Remote = remote
 	// Check the loaded repositories
 	if options.Uri != "" {
 		loadedRepo, ok := this.Repositories[options.Uri]
@@ -99,6 +128,7 @@ func (this *Graph) load(remote string, options LoadOptions, tracer *sourcecode.T
 				this.logger.LeveledPrintf(log.LevelError, "Conflict source of repository [%s]. Loaded [%s] Requested [%s]\n", options.Uri, loadedRepo.Uri, remote)
 				return nil, errors.New("Conflict repository source")
 			}
+			tracer.SetOutcome(sourcecode.TraceOutcomeCached)
 			// Resolve this repository
 			if err := this.resolve(loadedRepo, options.Targets, tracer); err != nil {
 				return nil, err
@@ -116,7 +146,18 @@ func (this *Graph) load(remote string, options LoadOptions, tracer *sourcecode.T
 	if loader == nil {
 		return nil, errors.New(fmt.Sprintf("Repository loader for type [%s] not found", t))
 	}
-	loadingRepo, err := loader.Load(remote, repoloader.LoadOptions{Branch: options.Branch, Commit: options.Commit}, this.ws)
+	loadingRepo, err := loader.Load(remote, repoloader.LoadOptions{
+		Branch:     options.Branch,
+		Commit:     options.Commit,
+		Tag:        options.Tag,
+		Subdir:     options.Subdir,
+		Checksum:   options.Checksum,
+		Submodules: options.Submodules,
+		LFS:        options.LFS,
+		LFSStore:   this.Options.LFSStore,
+		Readonly:   resolution.Source == ResolutionSourceFinder,
+		Root:       root,
+	}, this.ws)
 	if err != nil {
 		return nil, err
 	}
@@ -140,6 +181,12 @@ func (this *Graph) load(remote string, options LoadOptions, tracer *sourcecode.T
 	} else {
 		// Add this repository
 		this.Repositories[loadingRepo.Uri] = loadingRepo
+		resolution.Uri = loadingRepo.Uri
+		resolution.Tag = loadingRepo.Metadata.ResolvedTag
+		this.Resolutions[loadingRepo.Uri] = resolution
+		if options.resolution == nil {
+			this.roots = append(this.roots, loadingRepo)
+		}
 		// Resolve this repository
 		if err := this.resolve(loadingRepo, options.Targets, tracer); err != nil {
 			return nil, err
@@ -161,6 +208,18 @@ func (this *Graph) resolve(r *spec.Repository, targets []string, tracer *sourcec
 		}
 	} else {
 		for _, targetName := range targets {
+			// Expand the target pattern
+			if targetUri := uri.ParseTargetUri(targetName); targetUri != nil && targetUri.IsPattern() {
+				names := r.Spec.GetTargetNamesByUri(targetUri)
+				if len(names) == 0 {
+					this.logger.LeveledPrintf(log.LevelError, "No target matches [%s] in repository [%s]\n", targetName, r.Uri)
+					return errors.New(fmt.Sprintf("No target matches [%s] in repository [%s]", targetName, r.Uri))
+				}
+				if err := this.resolve(r, names, tracer); err != nil {
+					return err
+				}
+				continue
+			}
 			targetSpec, ok := r.Spec.Targets[targetName]
 			if !ok {
 				this.logger.LeveledPrintf(log.LevelError, "Target [%s] not found in repository [%s]\n", targetName, r.Uri)
@@ -175,7 +234,7 @@ func (this *Graph) resolve(r *spec.Repository, targets []string, tracer *sourcec
 	return nil
 }
 
-func (this *Graph) loadTarget(targetName string, targetSpec *spec.TargetSpec, r *spec.Repository, tracer *sourcecode.Tracer) (*spec.Target, error) {
+func (this *Graph) loadTarget(targetName string, targetSpec *spec.TargetSpec, r *spec.Repository, tracer *sourcecode.Tracer) (target *spec.Target, err error) {
 	this.logger.LeveledPrintf(log.LevelDebug, "Load target [%s] from repository [%s]\n", targetName, r.Uri)
 	targetKey := spec.GetTargetKey(targetName, r)
 	// Check loop
@@ -191,7 +250,9 @@ func (this *Graph) loadTarget(targetName string, targetSpec *spec.TargetSpec, r
 	}
 	// Push into tracer
 	tracer.Push(sourcecode.TraceTypeTarget, targetKey, targetName)
-	defer tracer.Pop()
+	defer func() {
+		tracer.PopWithError(err)
+	}()
 	this.logger.LeveledPrintf(log.LevelInfo, "Loading %s\n", tracer.String())
 	target = &spec.Target{
 		Name:       targetName,
@@ -206,6 +267,12 @@ func (this *Graph) loadTarget(targetName string, targetSpec *spec.TargetSpec, r
 		}
 		_, ok := this.Targets[depSpec.Key()]
 		if ok {
+			if depSpec.Repository != r.Uri {
+				// Request the repository, the conflicting requests are checked even if the target is loaded
+				if _, err := this.requestRepository(depSpec.Repository, r); err != nil {
+					return nil, err
+				}
+			}
 			continue
 		}
 		// Resolve this dependency
@@ -218,11 +285,13 @@ func (this *Graph) loadTarget(targetName string, targetSpec *spec.TargetSpec, r
 	return target, nil
 }
 
-func (this *Graph) resolveTargetDependency(name, targetName, repository string, target *spec.Target, tracer *sourcecode.Tracer) error {
+func (this *Graph) resolveTargetDependency(name, targetName, repository string, target *spec.Target, tracer *sourcecode.Tracer) (err error) {
 	this.logger.LeveledPrintf(log.LevelDebug, "Resolve target dependency [%s] from repository [%s] target [%s]\n", name, repository, targetName)
 	// Push tracer
 	tracer.Push(sourcecode.TraceTypeDependency, spec.GetTargetDependencyKey(name, targetName, repository), name)
-	defer tracer.Pop()
+	defer func() {
+		tracer.PopWithError(err)
+	}()
 	this.logger.LeveledPrintf(log.LevelInfo, "Loading %s\n", tracer.String())
 	// Load the repository and target
 	if repository == target.Repository.Uri {
@@ -235,36 +304,32 @@ func (this *Graph) resolveTargetDependency(name, targetName, repository string,
 		_, err := this.loadTarget(targetName, targetSpec, target.Repository, tracer)
 		return err
 	}
-	// Get the repository reference info
-	refer, ok := target.Repository.Spec.References[repository]
-	if !ok {
-		this.logger.LeveledPrintf(log.LevelError, "Repository reference of [%s] not found\n", repository)
-		return errors.New("Repository reference not found")
+	// Get the effective repository reference
+	refer, err := this.requestRepository(repository, target.Repository)
+	if err != nil {
+		return err
 	}
-	remote := refer.Remote
-	// Check the local
-	if this.Options.UseLocalDependency && !this.Options.DisableFinder && refer.Finder.Type != "" {
-		// Find the repository by finder
-		finder := repofinder.GetFinder(refer.Finder.Type)
-		if finder == nil {
-			this.logger.LeveledPrintf(log.LevelWarn, "Repository finder for [%s] with type [%s] not found\n", repository, refer.Finder.Type)
-		} else {
-			paths, err := finder.Find(this.ws, refer.Finder.Params)
-			if err != nil {
-				this.logger.LeveledPrintf(log.LevelWarn, "Failed to find repository for [%s] with type [%s] error: %s\n", repository, refer.Finder.Type, err)
-			} else if len(paths) > 2 {
-				this.logger.LeveledPrintf(log.LevelWarn, "Too many repository found by finder [%s] for repository [%s], found: %s\n", refer.Finder.Type, repository, strings.Join(paths, ", "))
-			} else if len(paths) == 1 {
-				this.logger.LeveledPrintf(log.LevelWarn, "Found repository for [%s] with type [%s]: %s\n", repository, refer.Finder.Type, paths[0])
-				remote = paths[0]
-			} else {
-				this.logger.LeveledPrintf(log.LevelDebug, "No repository found for [%s] with type [%s]\n", repository, refer.Finder.Type)
-			}
-		}
+	resolution, err := this.resolveRepositoryRemote(repository, refer, target)
+	if err != nil {
+		return err
+	}
+	// Use the locked commit
+	branch, commit, tag := refer.Branch, refer.Commit, refer.Tag
+	lockedCommit := this.getLockedCommit(repository, refer)
+	if lockedCommit != "" {
+		branch, commit, tag = "", lockedCommit, ""
 	}
 	// Load it
-	_, err := this.load(remote, LoadOptions{Uri: repository, Branch: refer.Branch, Commit: refer.Commit}, tracer)
-	return err
+	repo, err := this.load(resolution.Remote, LoadOptions{Uri: repository, Type: refer.Type, Branch: branch, Commit: commit, Tag: tag, Subdir: refer.Subdir, Checksum: refer.Checksum, Submodules: refer.Options.Submodules, LFS: refer.Options.LFS, resolution: resolution}, tracer)
+	if err != nil {
+		return err
+	}
+	if lockedCommit != "" && repo.Metadata.Commit != lockedCommit {
+		this.logger.LeveledPrintf(log.LevelError, "Repository [%s] is loaded from [%s] at commit [%s] which is different from the locked commit [%s], check out the locked commit or update the lock\n", repository, resolution.Remote, repo.Metadata.Commit, lockedCommit)
+		return errors.New("Mismatch locked commit")
+	}
+	// Done
+	return nil
 }
 
 // The visitor to traverse the graph
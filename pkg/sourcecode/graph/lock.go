@@ -0,0 +1,79 @@
+// Author: lipixun
+// Created Time : 二 12/27 14:26:10 2016
+//
+// File Name: lock.go
+// Description:
+//	Lock the referenced repositories to exact commits
+package graph
+
+import (
+	"fmt"
+	"github.com/ops-openlight/openlight/pkg/log"
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"sort"
+)
+
+// Generate the lock of all referenced repositories in current graph
+func (this *Graph) GenerateLock() *spec.RepositoryLock {
+	lock := &spec.RepositoryLock{Repositories: make(map[string]*spec.RepositoryLockEntry)}
+	for uri, refer := range this.References {
+		repo, ok := this.Repositories[uri]
+		if !ok {
+			continue
+		}
+		lock.Repositories[uri] = &spec.RepositoryLockEntry{
+			Remote:      refer.Remote,
+			Branch:      refer.Branch,
+			Commit:      refer.Commit,
+			Tag:         refer.Tag,
+			Resolved:    repo.Metadata.Commit,
+			ResolvedTag: repo.Metadata.ResolvedTag,
+		}
+		if resolution, ok := this.Resolutions[uri]; ok {
+			lock.Repositories[uri].Source = resolution.Source
+		}
+	}
+	return lock
+}
+
+// Check if the lock is stale relative to the repository references in current graph
+// Returns:
+// 	The problems found, empty means the lock is up to date
+func (this *Graph) CheckLock(lock *spec.RepositoryLock) []string {
+	var problems []string
+	for uri, refer := range this.References {
+		entry, ok := lock.Repositories[uri]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("Repository [%s] is not locked", uri))
+		} else if !entry.Match(refer) {
+			problems = append(problems, fmt.Sprintf("Repository [%s] reference changed, locked [remote=%s branch=%s commit=%s tag=%s] current [remote=%s branch=%s commit=%s tag=%s]",
+				uri, entry.Remote, entry.Branch, entry.Commit, entry.Tag, refer.Remote, refer.Branch, refer.Commit, refer.Tag))
+		} else if entry.Resolved == "" {
+			problems = append(problems, fmt.Sprintf("Repository [%s] has no resolved commit", uri))
+		}
+	}
+	for uri := range lock.Repositories {
+		if _, ok := this.References[uri]; !ok {
+			problems = append(problems, fmt.Sprintf("Repository [%s] is locked but not referenced", uri))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// Get the locked commit of the referenced repository, returns empty string if not locked or the lock is stale
+func (this *Graph) getLockedCommit(repository string, refer *spec.RepositoryReferenceSpec) string {
+	if this.Options.Lock == nil {
+		return ""
+	}
+	entry, ok := this.Options.Lock.Repositories[repository]
+	if !ok {
+		this.logger.LeveledPrintf(log.LevelWarn, "Repository [%s] is not locked\n", repository)
+		return ""
+	}
+	if !entry.Match(refer) {
+		this.logger.LeveledPrintf(log.LevelWarn, "Lock of repository [%s] is stale, ignored\n", repository)
+		return ""
+	}
+	return entry.Resolved
+}
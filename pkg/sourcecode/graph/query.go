@@ -0,0 +1,168 @@
+// Author: lipixun
+// Created Time : 四 12/29 10:24:51 2016
+//
+// File Name: query.go
+// Description:
+//	The queries of the graph: reverse dependencies, topological order and subgraph
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"sort"
+	"strings"
+)
+
+// Get the targets depending on the target
+// Parameters:
+// 	target 			The target
+// 	transitive 		Get the targets depending on the target transitively or only directly
+// Returns:
+// 	The dependent targets sorted by key
+func (this *Graph) GetDependents(target *spec.Target, transitive bool) []*spec.Target {
+	index := this.getReverseDependencyIndex()
+	dependents := make(map[string]*spec.Target)
+	queue := []*spec.Target{target}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range index[current.Key()] {
+			if _, ok := dependents[dependent.Key()]; ok || dependent.Key() == target.Key() {
+				continue
+			}
+			dependents[dependent.Key()] = dependent
+			if transitive {
+				queue = append(queue, dependent)
+			}
+		}
+	}
+	return sortTargets(dependents)
+}
+
+// Sort the targets in topological order, the dependencies are placed before the dependents
+// The targets with the same depth are sorted by key to keep the order stable
+// Parameters:
+// 	targets 		The targets to sort, nil means all targets of the graph. The dependencies not in targets are ignored
+func (this *Graph) TopologicalSort(targets []*spec.Target) ([]*spec.Target, error) {
+	if targets == nil {
+		for _, target := range this.Targets {
+			targets = append(targets, target)
+		}
+	}
+	selected := make(map[string]*spec.Target)
+	for _, target := range targets {
+		selected[target.Key()] = target
+	}
+	// Count the dependencies in selected targets
+	inDegrees := make(map[string]int)
+	dependents := make(map[string][]string)
+	for key, target := range selected {
+		inDegrees[key] += 0
+		added := make(map[string]bool)
+		for _, depSpec := range target.Spec.Deps {
+			depKey := depSpec.Key()
+			if _, ok := selected[depKey]; !ok || added[depKey] {
+				continue
+			}
+			added[depKey] = true
+			inDegrees[key]++
+			dependents[depKey] = append(dependents[depKey], key)
+		}
+	}
+	// Kahn's algorithm
+	var ready []string
+	for key, degree := range inDegrees {
+		if degree == 0 {
+			ready = append(ready, key)
+		}
+	}
+	var sorted []*spec.Target
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		var next []string
+		for _, key := range ready {
+			sorted = append(sorted, selected[key])
+			for _, dependent := range dependents[key] {
+				inDegrees[dependent]--
+				if inDegrees[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		ready = next
+	}
+	if len(sorted) != len(selected) {
+		var loop []string
+		for key, degree := range inDegrees {
+			if degree > 0 {
+				loop = append(loop, key)
+			}
+		}
+		sort.Strings(loop)
+		return nil, errors.New(fmt.Sprintf("Dependency loop found in targets: %s", strings.Join(loop, ", ")))
+	}
+	return sorted, nil
+}
+
+// Extract the subgraph which contains the targets and all their dependencies (transitively)
+// The subgraph shares the targets and repositories with current graph
+func (this *Graph) Subgraph(targets []*spec.Target) (*Graph, error) {
+	subgraph, err := New(this.ws, this.Options)
+	if err != nil {
+		return nil, err
+	}
+	queue := append([]*spec.Target{}, targets...)
+	for len(queue) > 0 {
+		target := queue[0]
+		queue = queue[1:]
+		if _, ok := subgraph.Targets[target.Key()]; ok {
+			continue
+		}
+		if _, ok := this.Targets[target.Key()]; !ok {
+			return nil, errors.New(fmt.Sprintf("Target [%s] not found in current graph", target.Key()))
+		}
+		subgraph.Targets[target.Key()] = target
+		subgraph.Repositories[target.Repository.Uri] = target.Repository
+		if resolution, ok := this.Resolutions[target.Repository.Uri]; ok {
+			subgraph.Resolutions[target.Repository.Uri] = resolution
+		}
+		for _, depSpec := range target.Spec.Deps {
+			depTarget, ok := this.Targets[depSpec.Key()]
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("Dependency target [%s] not found", depSpec.Key()))
+			}
+			queue = append(queue, depTarget)
+		}
+	}
+	// Done
+	return subgraph, nil
+}
+
+// Get the index of the targets depending on a target directly, key is the target key
+func (this *Graph) getReverseDependencyIndex() map[string][]*spec.Target {
+	index := make(map[string][]*spec.Target)
+	for _, target := range this.Targets {
+		added := make(map[string]bool)
+		for _, depSpec := range target.Spec.Deps {
+			if added[depSpec.Key()] {
+				continue
+			}
+			added[depSpec.Key()] = true
+			index[depSpec.Key()] = append(index[depSpec.Key()], target)
+		}
+	}
+	return index
+}
+
+// Sort the targets by key
+func sortTargets(targets map[string]*spec.Target) []*spec.Target {
+	var sorted []*spec.Target
+	for _, target := range targets {
+		sorted = append(sorted, target)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Key() < sorted[j].Key()
+	})
+	return sorted
+}
@@ -0,0 +1,173 @@
+// Author: lipixun
+// Created Time : 四 12/29 19:40:08 2016
+//
+// File Name: query_test.go
+// Description:
+//
+package graph
+
+import (
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"github.com/ops-openlight/openlight/pkg/workspace"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testQueryRepositoryUri = "github.com/ops-openlight/test"
+
+// Create an empty graph with the workspace in a temp directory
+// Returns:
+// 	The graph and the temp directory, the caller should remove the directory
+func newTestGraph(t *testing.T, graphOptions GraphOptions) (*Graph, string) {
+	path, err := ioutil.TempDir("", "graph")
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := workspace.NewWorkspaceOptions()
+	options.Dir.GlobalPath = filepath.Join(path, "global")
+	options.Dir.UserPath = filepath.Join(path, "user")
+	ws, err := workspace.New(options, nil)
+	if err != nil {
+		os.RemoveAll(path)
+		t.Fatal(err)
+	}
+	g, err := New(ws, graphOptions)
+	if err != nil {
+		os.RemoveAll(path)
+		t.Fatal(err)
+	}
+	return g, path
+}
+
+// Create a graph of the targets, key is target name, value is the names of the dependency targets
+func newTestQueryGraph(t *testing.T, targets map[string][]string) (*Graph, func()) {
+	g, path := newTestGraph(t, GraphOptions{})
+	repo := &spec.Repository{Uri: testQueryRepositoryUri}
+	g.Repositories[repo.Uri] = repo
+	for name, deps := range targets {
+		targetSpec := &spec.TargetSpec{Deps: make(map[string]*spec.TargetDependencySpec)}
+		for _, dep := range deps {
+			targetSpec.Deps[dep] = &spec.TargetDependencySpec{Target: dep, Repository: repo.Uri}
+		}
+		target := &spec.Target{Name: name, Repository: repo, Spec: targetSpec}
+		g.Targets[target.Key()] = target
+	}
+	return g, func() { os.RemoveAll(path) }
+}
+
+func getTestQueryTarget(g *Graph, name string) *spec.Target {
+	return g.Targets[spec.GetTargetKey(name, g.Repositories[testQueryRepositoryUri])]
+}
+
+func getTestQueryTargetNames(targets []*spec.Target) []string {
+	names := []string{}
+	for _, target := range targets {
+		names = append(names, target.Name)
+	}
+	return names
+}
+
+// The diamond: app -> (api, web) -> lib -> base, and tool is isolated
+var testQueryDiamond = map[string][]string{
+	"app":  {"api", "web"},
+	"api":  {"lib"},
+	"web":  {"lib"},
+	"lib":  {"base"},
+	"base": nil,
+	"tool": nil,
+}
+
+func TestGetDependents(t *testing.T) {
+	g, clean := newTestQueryGraph(t, testQueryDiamond)
+	defer clean()
+	for _, c := range []struct {
+		Target     string
+		Transitive bool
+		Expected   []string
+	}{
+		{"base", false, []string{"lib"}},
+		{"base", true, []string{"api", "app", "lib", "web"}},
+		{"lib", false, []string{"api", "web"}},
+		{"lib", true, []string{"api", "app", "web"}},
+		{"api", true, []string{"app"}},
+		{"app", true, []string{}},
+		{"tool", true, []string{}},
+	} {
+		actual := getTestQueryTargetNames(g.GetDependents(getTestQueryTarget(g, c.Target), c.Transitive))
+		if !reflect.DeepEqual(actual, c.Expected) {
+			t.Errorf("Mismatch dependents of [%s] transitive [%v]. Expected %v Actually %v", c.Target, c.Transitive, c.Expected, actual)
+		}
+	}
+	// The loop
+	g, clean = newTestQueryGraph(t, map[string][]string{"a": {"b"}, "b": {"a"}})
+	defer clean()
+	if actual := getTestQueryTargetNames(g.GetDependents(getTestQueryTarget(g, "a"), true)); !reflect.DeepEqual(actual, []string{"b"}) {
+		t.Errorf("Mismatch dependents in loop. Expected [b] Actually %v", actual)
+	}
+}
+
+func TestTopologicalSort(t *testing.T) {
+	for _, c := range []struct {
+		Targets  map[string][]string
+		Select   []string // nil means all targets
+		Expected []string
+		Valid    bool
+	}{
+		{testQueryDiamond, nil, []string{"base", "tool", "lib", "api", "web", "app"}, true},
+		{testQueryDiamond, []string{"app", "web", "base"}, []string{"base", "web", "app"}, true},
+		{testQueryDiamond, []string{"app"}, []string{"app"}, true},
+		{map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}, "d": nil}, nil, nil, false},
+		{map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}, "d": nil}, []string{"a", "b", "d"}, []string{"b", "d", "a"}, true},
+	} {
+		g, clean := newTestQueryGraph(t, c.Targets)
+		var targets []*spec.Target
+		for _, name := range c.Select {
+			targets = append(targets, getTestQueryTarget(g, name))
+		}
+		sorted, err := g.TopologicalSort(targets)
+		clean()
+		if c.Valid && err != nil {
+			t.Errorf("Failed to sort targets %v, error: %s", c.Select, err)
+		} else if !c.Valid && err == nil {
+			t.Errorf("Expect dependency loop error when sorting targets %v", c.Select)
+		} else if c.Valid && !reflect.DeepEqual(getTestQueryTargetNames(sorted), c.Expected) {
+			t.Errorf("Mismatch sorted targets %v. Expected %v Actually %v", c.Select, c.Expected, getTestQueryTargetNames(sorted))
+		}
+	}
+}
+
+func TestSubgraph(t *testing.T) {
+	g, clean := newTestQueryGraph(t, testQueryDiamond)
+	defer clean()
+	for _, c := range []struct {
+		Targets  []string
+		Expected []string
+	}{
+		{[]string{"app"}, []string{"api", "app", "base", "lib", "web"}},
+		{[]string{"web", "tool"}, []string{"base", "lib", "tool", "web"}},
+		{[]string{"base"}, []string{"base"}},
+	} {
+		var targets []*spec.Target
+		for _, name := range c.Targets {
+			targets = append(targets, getTestQueryTarget(g, name))
+		}
+		subgraph, err := g.Subgraph(targets)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual := getTestQueryTargetNames(sortTargets(subgraph.Targets))
+		if !reflect.DeepEqual(actual, c.Expected) {
+			t.Errorf("Mismatch subgraph of %v. Expected %v Actually %v", c.Targets, c.Expected, actual)
+		}
+		if subgraph.Repositories[testQueryRepositoryUri] == nil {
+			t.Errorf("Repository not found in subgraph of %v", c.Targets)
+		}
+	}
+	// The target not in graph
+	if _, err := g.Subgraph([]*spec.Target{{Name: "unknown", Repository: g.Repositories[testQueryRepositoryUri], Spec: &spec.TargetSpec{}}}); err == nil {
+		t.Error("Expect error when extracting subgraph of unknown target")
+	}
+}
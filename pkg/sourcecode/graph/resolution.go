@@ -0,0 +1,139 @@
+// Author: lipixun
+// Created Time : 二 12/27 10:15:36 2016
+//
+// File Name: resolution.go
+// Description:
+//	Resolve the remote of the referenced repository
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ops-openlight/openlight/pkg/log"
+	"github.com/ops-openlight/openlight/pkg/sourcecode/repofinder"
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"sort"
+	"strings"
+)
+
+const (
+	ResolutionPolicyPreferLocal  = "prefer-local"  // Use the local repository found by finder, fallback to the remote
+	ResolutionPolicyRequireLocal = "require-local" // The local repository must be found by finder
+	ResolutionPolicyPreferRemote = "prefer-remote" // Use the remote, fallback to the local repository found by finder
+
+	DefaultResolutionPolicy = ResolutionPolicyPreferLocal
+
+	ResolutionSourceRoot      = "root"      // The repository is loaded directly
+	ResolutionSourceOverwrite = "overwrite" // The remote is overwritten
+	ResolutionSourceFinder    = "finder"    // The repository is found by finder
+	ResolutionSourceRemote    = "remote"    // The remote defined in repository reference
+)
+
+// Check if the resolution policy is valid
+func IsValidResolutionPolicy(policy string) bool {
+	return policy == ResolutionPolicyPreferLocal || policy == ResolutionPolicyRequireLocal || policy == ResolutionPolicyPreferRemote
+}
+
+// Where the repository comes from
+type RepositoryResolution struct {
+	Uri        string   `json:"uri"`
+	Source     string   `json:"source"`               // The resolution source
+	Finder     string   `json:"finder,omitempty"`     // The finder type if resolved by finder
+	Remote     string   `json:"remote"`               // The remote (or local path) which the repository is loaded from
+	Candidates []string `json:"candidates,omitempty"` // The paths found by finder
+	Tag        string   `json:"tag,omitempty"`        // The tag resolved from the tag (or semver range) reference
+}
+
+func (this *RepositoryResolution) String() string {
+	var str string
+	switch this.Source {
+	case ResolutionSourceFinder:
+		str = fmt.Sprintf("%s --> %s (by finder [%s])", this.Uri, this.Remote, this.Finder)
+	default:
+		str = fmt.Sprintf("%s --> %s (by %s)", this.Uri, this.Remote, this.Source)
+	}
+	if this.Tag != "" {
+		str += fmt.Sprintf(" at tag [%s]", this.Tag)
+	}
+	return str
+}
+
+// Check if the repository is resolved from local (by finder or overwrite), which may have commits not pushed to the referenced remote
+func (this *RepositoryResolution) IsLocal() bool {
+	return this.Source == ResolutionSourceFinder || this.Source == ResolutionSourceOverwrite
+}
+
+// Get the resolution report of all loaded repositories, sorted by uri
+func (this *Graph) GetResolutionReport() []*RepositoryResolution {
+	var resolutions []*RepositoryResolution
+	for _, resolution := range this.Resolutions {
+		resolutions = append(resolutions, resolution)
+	}
+	sort.Slice(resolutions, func(i, j int) bool {
+		return resolutions[i].Uri < resolutions[j].Uri
+	})
+	return resolutions
+}
+
+// Get the resolution policy
+func (this *Graph) getResolutionPolicy() string {
+	if this.Options.ResolutionPolicy == "" {
+		return DefaultResolutionPolicy
+	}
+	return this.Options.ResolutionPolicy
+}
+
+// Resolve the remote of the referenced repository by the resolution policy
+// NOTE: The remote overwrites are applied when loading the repository
+func (this *Graph) resolveRepositoryRemote(repository string, refer *spec.RepositoryReferenceSpec, target *spec.Target) (*RepositoryResolution, error) {
+	if _, ok := this.RemoteOverwrites[repository]; ok {
+		return &RepositoryResolution{Uri: repository, Source: ResolutionSourceOverwrite}, nil
+	}
+	policy := this.getResolutionPolicy()
+	if !IsValidResolutionPolicy(policy) {
+		return nil, errors.New(fmt.Sprintf("Unknown resolution policy [%s]", policy))
+	}
+	if policy == ResolutionPolicyPreferRemote && refer.Remote != "" {
+		return &RepositoryResolution{Uri: repository, Source: ResolutionSourceRemote, Remote: refer.Remote}, nil
+	}
+	// Find the local repository
+	paths, err := this.findLocalRepository(repository, refer, target)
+	if err != nil {
+		if policy == ResolutionPolicyRequireLocal {
+			return nil, err
+		}
+		this.logger.LeveledPrintf(log.LevelWarn, "%s\n", err)
+	} else if len(paths) > 1 {
+		this.logger.LeveledPrintf(log.LevelError, "Multiple repositories found by finder [%s] for repository [%s], found: %s\n", refer.Finder.Type, repository, strings.Join(paths, ", "))
+		return nil, errors.New(fmt.Sprintf("Ambiguous local repository of [%s]", repository))
+	} else if len(paths) == 1 {
+		this.logger.LeveledPrintf(log.LevelInfo, "Found repository for [%s] with type [%s]: %s\n", repository, refer.Finder.Type, paths[0])
+		return &RepositoryResolution{Uri: repository, Source: ResolutionSourceFinder, Finder: refer.Finder.Type, Remote: paths[0], Candidates: paths}, nil
+	} else {
+		this.logger.LeveledPrintf(log.LevelDebug, "No repository found for [%s] with type [%s]\n", repository, refer.Finder.Type)
+	}
+	if policy == ResolutionPolicyRequireLocal {
+		return nil, errors.New(fmt.Sprintf("Local repository of [%s] is required but not found", repository))
+	}
+	// Use the remote
+	if refer.Remote == "" {
+		return nil, errors.New(fmt.Sprintf("No remote defined for repository [%s] and no local repository found", repository))
+	}
+	return &RepositoryResolution{Uri: repository, Source: ResolutionSourceRemote, Remote: refer.Remote}, nil
+}
+
+// Find the local repository by finder, returns nothing if finder is disabled or not defined
+func (this *Graph) findLocalRepository(repository string, refer *spec.RepositoryReferenceSpec, target *spec.Target) ([]string, error) {
+	if !this.Options.UseLocalDependency || this.Options.DisableFinder || refer.Finder.Type == "" {
+		return nil, nil
+	}
+	finder := repofinder.GetFinder(refer.Finder.Type)
+	if finder == nil {
+		return nil, errors.New(fmt.Sprintf("Repository finder for [%s] with type [%s] not found", repository, refer.Finder.Type))
+	}
+	paths, err := finder.Find(this.ws, &repofinder.FindContext{Uri: repository, RepositoryPath: target.Repository.Local.Path}, refer.Finder.Params)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Failed to find repository for [%s] with type [%s] error: %s", repository, refer.Finder.Type, err))
+	}
+	return paths, nil
+}
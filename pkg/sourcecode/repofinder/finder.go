@@ -7,20 +7,103 @@
 package repofinder
 
 import (
+	"errors"
+	"fmt"
+	"github.com/ops-openlight/openlight/pkg/util"
 	"github.com/ops-openlight/openlight/pkg/workspace"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+const (
+	FinderParamPaths = "paths" // The additional search roots, used by paths and remote finders
 )
 
 var (
 	finders map[string]Finder = map[string]Finder{
-		FinderTypeGolang: NewGolangFinder(),
-		FinderTypePython: NewPythonFinder(),
+		FinderTypeGolang:  NewGolangFinder(),
+		FinderTypePython:  NewPythonFinder(),
+		FinderTypeSibling: NewSiblingFinder(),
+		FinderTypePaths:   NewPathsFinder(),
+		FinderTypeRemote:  NewRemoteFinder(),
 	}
 )
 
 type Finder interface {
-	Find(ws *workspace.Workspace, params map[string]interface{}) ([]string, error)
+	Find(ws *workspace.Workspace, ctx *FindContext, params map[string]interface{}) ([]string, error)
+}
+
+// The context of finding a repository
+type FindContext struct {
+	Uri            string // The referenced repository uri
+	RepositoryPath string // The local path of the repository which references the repository to find
+}
+
+// Get the default name of the repository to find, which is the last element of the referenced uri
+func (this *FindContext) GetDefaultName() string {
+	if this == nil || this.Uri == "" {
+		return ""
+	}
+	return path.Base(this.Uri)
 }
 
 func GetFinder(t string) Finder {
 	return finders[t]
 }
+
+// Append path to paths if not existed
+func appendUniquePath(paths []string, path string) []string {
+	for _, p := range paths {
+		if p == path {
+			return paths
+		}
+	}
+	return append(paths, path)
+}
+
+// Get the search roots from the workspace options and the paths parameter
+// NOTE: The search roots may be glob patterns, the matched directories are returned
+func getSearchRoots(ws *workspace.Workspace, params map[string]interface{}) ([]string, error) {
+	var patterns []string
+	if ws != nil {
+		patterns = append(patterns, ws.Options.Finder.SearchPaths...)
+	}
+	if _paths, ok := params[FinderParamPaths]; ok {
+		paths, ok := _paths.([]interface{})
+		if !ok {
+			return nil, errors.New("Invalid value of paths parameter")
+		}
+		for _, _path := range paths {
+			p, ok := _path.(string)
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("Invalid value [%v] of paths parameter", _path))
+			}
+			patterns = append(patterns, p)
+		}
+	}
+	var roots []string
+	for _, pattern := range patterns {
+		pattern, err := util.GetRealPath(pattern)
+		if err != nil {
+			return nil, err
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid search path [%s], error: %s", pattern, err))
+		}
+		for _, match := range matches {
+			if isDir(match) {
+				roots = appendUniquePath(roots, match)
+			}
+		}
+	}
+	// Done
+	return roots, nil
+}
+
+// Check if the path is an existing directory
+func isDir(p string) bool {
+	info, err := os.Stat(p)
+	return err == nil && info.IsDir()
+}
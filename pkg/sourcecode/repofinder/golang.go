@@ -8,15 +8,25 @@
 // 		package 		string 	The package to find
 // 		parent  		int 	The parent level count
 //
+// 	The package is searched in the following paths, all found paths (deduplicated) will be returned and multiple ones mean ambiguity:
+// 		1. The modules used by go.work and the replace directives of go.mod (found from the project path)
+// 		2. The src dir of every GOPATH entry (go env GOPATH if GOPATH env is not set)
+// 		3. The go module cache (the newest downloaded version). Only in module mode (GO111MODULE is on, or not off and go.mod is found from the project path)
+//
 
 package repofinder
 
 import (
+	"bufio"
 	"errors"
 	"github.com/ops-openlight/openlight/pkg/workspace"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
 const (
@@ -24,6 +34,9 @@ const (
 
 	GolangFinderParamPackage = "package"
 	GolangFinderParamParent  = "parent"
+
+	GolangWorkFileName   = "go.work"
+	GolangModuleFileName = "go.mod"
 )
 
 type GolangFinder struct{}
@@ -32,7 +45,7 @@ func NewGolangFinder() GolangFinder {
 	return GolangFinder{}
 }
 
-func (this GolangFinder) Find(ws *workspace.Workspace, params map[string]interface{}) ([]string, error) {
+func (this GolangFinder) Find(ws *workspace.Workspace, ctx *FindContext, params map[string]interface{}) ([]string, error) {
 	_package, ok := params[GolangFinderParamPackage]
 	if !ok {
 		return nil, errors.New("Require package parameter")
@@ -49,26 +62,306 @@ func (this GolangFinder) Find(ws *workspace.Workspace, params map[string]interfa
 			return nil, errors.New("Invalid value of parent parameter")
 		}
 	}
-	// Get go path
-	var goPath string
-	for _, environ := range os.Environ() {
-		if strings.HasPrefix(environ, "GOPATH=") {
-			// Found the go path
-			goPath = environ[7:]
-			break
+	// Find the package
+	var projectPath string
+	if ws != nil && ws.Dir.Project != nil {
+		projectPath = ws.Dir.Project.RootPath()
+	}
+	var candidates []string
+	candidates = append(candidates, findGolangPackageInLocalModules(projectPath, pkg)...)
+	goPaths := getGoPaths()
+	for _, goPath := range goPaths {
+		candidates = append(candidates, filepath.Join(goPath, "src", pkg))
+	}
+	if isGolangModuleMode(projectPath) {
+		// Only the newest version in module cache is used
+		for _, path := range findGolangPackageInModuleCache(getGoModCache(goPaths), pkg) {
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				candidates = append(candidates, path)
+				break
+			}
 		}
 	}
-	// Find the package
-	path := filepath.Join(goPath, pkg)
-	if _, err := os.Stat(path); err != nil {
-		// Not found
-		return nil, nil
+	// Get all found candidates
+	var paths []string
+	found := make(map[string]bool)
+	for _, path := range candidates {
+		if info, err := os.Stat(path); err != nil || !info.IsDir() {
+			// Not found
+			continue
+		}
+		// Get with parent
+		path = filepath.Clean(path)
+		for i := 0; i < parent; i++ {
+			path = filepath.Dir(path)
+		}
+		if !found[path] {
+			found[path] = true
+			paths = append(paths, path)
+		}
+	}
+	// Done
+	return paths, nil
+}
+
+// Check if go command works in module mode for the project
+func isGolangModuleMode(projectPath string) bool {
+	mode := os.Getenv("GO111MODULE")
+	if mode == "" {
+		mode = getGoEnv("GO111MODULE")
+	}
+	switch mode {
+	case "off":
+		return false
+	case "on":
+		return true
+	default:
+		return projectPath != "" && findFileUpward(projectPath, GolangModuleFileName) != ""
+	}
+}
+
+// Get all go path entries
+func getGoPaths() []string {
+	goPath := os.Getenv("GOPATH")
+	if goPath == "" {
+		goPath = getGoEnv("GOPATH")
+	}
+	var goPaths []string
+	for _, p := range filepath.SplitList(goPath) {
+		if p != "" {
+			goPaths = append(goPaths, p)
+		}
+	}
+	return goPaths
+}
+
+// Get the go module cache path
+func getGoModCache(goPaths []string) string {
+	if modCache := os.Getenv("GOMODCACHE"); modCache != "" {
+		return modCache
 	}
-	// Found it
-	// Get with parent
-	for i := 0; i < parent; i++ {
-		path = filepath.Dir(path)
+	if modCache := getGoEnv("GOMODCACHE"); modCache != "" {
+		return modCache
+	}
+	if len(goPaths) > 0 {
+		return filepath.Join(goPaths[0], "pkg", "mod")
+	}
+	return ""
+}
+
+// Get go env by go command, returns empty string if go command is not available
+func getGoEnv(name string) string {
+	rtn, err := exec.Command("go", "env", name).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(rtn))
+}
+
+// Find the package in the modules used by go.work and replaced in go.mod
+func findGolangPackageInLocalModules(projectPath, pkg string) []string {
+	if projectPath == "" {
+		return nil
+	}
+	var paths []string
+	// Get the go.work
+	workFile := os.Getenv("GOWORK")
+	if workFile == "" {
+		workFile = findFileUpward(projectPath, GolangWorkFileName)
+	}
+	if workFile != "" && workFile != "off" {
+		directives, err := parseGoModDirectives(workFile)
+		if err == nil {
+			for _, args := range directives["use"] {
+				dir := args[0]
+				if !filepath.IsAbs(dir) {
+					dir = filepath.Join(filepath.Dir(workFile), dir)
+				}
+				if path := getGolangPackagePathInModule(dir, pkg); path != "" {
+					paths = append(paths, path)
+				}
+			}
+			paths = append(paths, getGolangPackagePathsByReplaces(filepath.Dir(workFile), directives["replace"], pkg)...)
+		}
+	}
+	// Get the go.mod
+	if modFile := findFileUpward(projectPath, GolangModuleFileName); modFile != "" {
+		if directives, err := parseGoModDirectives(modFile); err == nil {
+			paths = append(paths, getGolangPackagePathsByReplaces(filepath.Dir(modFile), directives["replace"], pkg)...)
+		}
+	}
+	// Done
+	return paths
+}
+
+// Get the package path in the module dir, returns empty string if the package doesn't belong to the module
+func getGolangPackagePathInModule(dir, pkg string) string {
+	directives, err := parseGoModDirectives(filepath.Join(dir, GolangModuleFileName))
+	if err != nil || len(directives["module"]) == 0 {
+		return ""
+	}
+	if rest, ok := trimGolangModulePrefix(pkg, directives["module"][0][0]); ok {
+		return filepath.Join(dir, filepath.FromSlash(rest))
+	}
+	return ""
+}
+
+// Get the package paths by the replace directives, only replaces to local directories are considered
+func getGolangPackagePathsByReplaces(baseDir string, replaces [][]string, pkg string) []string {
+	var paths []string
+	for _, args := range replaces {
+		// The args is: old [version] => new [version]
+		var index int
+		for index = 0; index < len(args) && args[index] != "=>"; index++ {
+		}
+		if index == 0 || index+1 >= len(args) {
+			continue
+		}
+		rest, ok := trimGolangModulePrefix(pkg, args[0])
+		if !ok {
+			continue
+		}
+		dir := args[index+1]
+		if !filepath.IsAbs(dir) && !strings.HasPrefix(dir, "./") && !strings.HasPrefix(dir, "../") {
+			// Not a local directory
+			continue
+		}
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(baseDir, dir)
+		}
+		paths = append(paths, filepath.Join(dir, filepath.FromSlash(rest)))
+	}
+	return paths
+}
+
+// Find the package in go module cache, all versions will be returned (newest first)
+func findGolangPackageInModuleCache(modCache, pkg string) []string {
+	if modCache == "" {
+		return nil
+	}
+	var paths []string
+	// Try each prefix of the package as the module path, the longest first
+	parts := strings.Split(pkg, "/")
+	for i := len(parts); i > 0; i-- {
+		module := strings.Join(parts[:i], "/")
+		escaped, ok := escapeGolangModulePath(module)
+		if !ok {
+			continue
+		}
+		dirs, err := filepath.Glob(filepath.Join(modCache, filepath.FromSlash(escaped)+"@*"))
+		if err != nil || len(dirs) == 0 {
+			continue
+		}
+		sort.Slice(dirs, func(i, j int) bool {
+			return compareGolangModuleVersion(dirs[i][strings.LastIndex(dirs[i], "@")+1:], dirs[j][strings.LastIndex(dirs[j], "@")+1:]) > 0
+		})
+		for _, dir := range dirs {
+			paths = append(paths, filepath.Join(append([]string{dir}, parts[i:]...)...))
+		}
+	}
+	return paths
+}
+
+// Compare the module versions (e.g. v1.9.0 and v1.18.0) by the numeric parts
+func compareGolangModuleVersion(a, b string) int {
+	aParts := strings.FieldsFunc(a, func(r rune) bool { return !unicode.IsDigit(r) })
+	bParts := strings.FieldsFunc(b, func(r rune) bool { return !unicode.IsDigit(r) })
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		x, _ := strconv.Atoi(aParts[i])
+		y, _ := strconv.Atoi(bParts[i])
+		if x != y {
+			return x - y
+		}
+	}
+	if len(aParts) != len(bParts) {
+		return len(aParts) - len(bParts)
+	}
+	return strings.Compare(a, b)
+}
+
+// Escape the module path as the go module cache does (upper case letter is replaced by ! and the lower case one)
+func escapeGolangModulePath(module string) (string, bool) {
+	var builder strings.Builder
+	for _, r := range module {
+		if r == '!' || r >= unicode.MaxASCII {
+			return "", false
+		}
+		if unicode.IsUpper(r) {
+			builder.WriteByte('!')
+			builder.WriteRune(unicode.ToLower(r))
+		} else {
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String(), true
+}
+
+// Trim the module path prefix of the package
+func trimGolangModulePrefix(pkg, module string) (string, bool) {
+	if pkg == module {
+		return "", true
+	}
+	if strings.HasPrefix(pkg, module+"/") {
+		return pkg[len(module)+1:], true
+	}
+	return "", false
+}
+
+// Find the file from the path upward to the root, returns empty string if not found
+func findFileUpward(path, name string) string {
+	for {
+		filename := filepath.Join(path, name)
+		if info, err := os.Stat(filename); err == nil && !info.IsDir() {
+			return filename
+		}
+		dir := filepath.Dir(path)
+		if dir == path {
+			return ""
+		}
+		path = dir
+	}
+}
+
+// Parse the directives of go.mod or go.work file
+// Returns:
+// 	The directive name to the args list, block directives (e.g. replace ( ... )) are flattened
+func parseGoModDirectives(filename string) (map[string][][]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	directives := make(map[string][][]string)
+	var block string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if index := strings.Index(line, "//"); index != -1 {
+			line = line[:index]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		for i, field := range fields {
+			fields[i] = strings.Trim(field, "\"`")
+		}
+		if block != "" {
+			if fields[0] == ")" {
+				block = ""
+			} else {
+				directives[block] = append(directives[block], fields)
+			}
+			continue
+		}
+		if len(fields) == 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+		if len(fields) > 1 {
+			directives[fields[0]] = append(directives[fields[0]], fields[1:])
+		}
 	}
 	// Done
-	return []string{path}, nil
+	return directives, scanner.Err()
 }
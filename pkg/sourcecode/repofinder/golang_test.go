@@ -0,0 +1,68 @@
+// Author: lipixun
+// Created Time : 四 12/29 22:05:51 2016
+//
+// File Name: golang_test.go
+// Description:
+//
+package repofinder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGolangFinderFind(t *testing.T) {
+	path, err := ioutil.TempDir("", "golangfinder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+	for _, dir := range []string{
+		"gopath1/src/github.com/test/lib",
+		"gopath2/src/github.com/test/lib",
+		"gopath2/src/github.com/test/other",
+		"modcache/github.com/test/lib@v1.9.0",
+		"modcache/github.com/test/lib@v1.10.0",
+	} {
+		if err := os.MkdirAll(filepath.Join(path, dir), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, value := range map[string]string{
+		"GOPATH":     filepath.Join(path, "gopath1") + string(filepath.ListSeparator) + filepath.Join(path, "gopath2"),
+		"GOMODCACHE": filepath.Join(path, "modcache"),
+		"GOWORK":     "off",
+	} {
+		defer os.Setenv(name, os.Getenv(name))
+		os.Setenv(name, value)
+	}
+	defer os.Setenv("GO111MODULE", os.Getenv("GO111MODULE"))
+	for _, c := range []struct {
+		Package  string
+		Parent   int
+		Module   string
+		Expected []string
+	}{
+		{"github.com/test/lib", 0, "off", []string{"gopath1/src/github.com/test/lib", "gopath2/src/github.com/test/lib"}},
+		{"github.com/test/lib", 0, "on", []string{"gopath1/src/github.com/test/lib", "gopath2/src/github.com/test/lib", "modcache/github.com/test/lib@v1.10.0"}},
+		{"github.com/test/other", 0, "on", []string{"gopath2/src/github.com/test/other"}},
+		{"github.com/test/lib", 2, "off", []string{"gopath1/src/github.com", "gopath2/src/github.com"}},
+		{"github.com/test/unknown", 0, "on", nil},
+	} {
+		os.Setenv("GO111MODULE", c.Module)
+		paths, err := NewGolangFinder().Find(nil, &FindContext{Uri: c.Package}, map[string]interface{}{GolangFinderParamPackage: c.Package, GolangFinderParamParent: c.Parent})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var expected []string
+		for _, p := range c.Expected {
+			expected = append(expected, filepath.Join(path, p))
+		}
+		if !reflect.DeepEqual(paths, expected) {
+			t.Errorf("Mismatch paths of package [%s] parent [%d] module [%s]. Expected %v Actually %v", c.Package, c.Parent, c.Module, expected, paths)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Author: lipixun
+// Created Time : 一 12/26 16:32:17 2016
+//
+// File Name: paths.go
+// Description:
+//	Find repository in the search paths
+//	Optional parameters:
+// 		name 			string 		The directory name (glob pattern is supported), use the last element of the referenced uri if not specified
+// 		paths 			[]string 	The additional search roots (glob pattern is supported)
+//
+// 	The search roots are defined by the workspace finder options and the paths parameter
+//
+
+package repofinder
+
+import (
+	"errors"
+	"github.com/ops-openlight/openlight/pkg/workspace"
+	"path/filepath"
+)
+
+const (
+	FinderTypePaths = "paths"
+
+	PathsFinderParamName = "name"
+)
+
+type PathsFinder struct{}
+
+func NewPathsFinder() PathsFinder {
+	return PathsFinder{}
+}
+
+func (this PathsFinder) Find(ws *workspace.Workspace, ctx *FindContext, params map[string]interface{}) ([]string, error) {
+	name := ctx.GetDefaultName()
+	if _name, ok := params[PathsFinderParamName]; ok {
+		name, ok = _name.(string)
+		if !ok {
+			return nil, errors.New("Invalid value of name parameter")
+		}
+	}
+	if name == "" {
+		return nil, errors.New("Require name parameter")
+	}
+	roots, err := getSearchRoots(ws, params)
+	if err != nil {
+		return nil, err
+	}
+	// Find in the search roots
+	var paths []string
+	for _, root := range roots {
+		matches, err := filepath.Glob(filepath.Join(root, name))
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if isDir(match) {
+				paths = appendUniquePath(paths, match)
+			}
+		}
+	}
+	// Done
+	return paths, nil
+}
@@ -7,15 +7,20 @@
 //	Required parameters:
 // 		module 			string 	The module name to find, required
 // 		parent  		int 	The parent level count
+// 		interpreter 	string 	The python interpreter. Will use PYTHON env, the python of current virtualenv (VIRTUAL_ENV env), python3 or python in order if not specified
+//
+// 	A module may resolve to several paths (e.g. namespace packages), all of them will be returned
 //
 
 package repofinder
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"github.com/ops-openlight/openlight/pkg/workspace"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
@@ -25,18 +30,25 @@ import (
 const (
 	FinderTypePython = "python"
 
-	PythonFinderParamModule = "module"
-	PythonFinderParamParent = "parent"
+	PythonFinderParamModule      = "module"
+	PythonFinderParamParent      = "parent"
+	PythonFinderParamInterpreter = "interpreter"
 
 	PythonTryImportTimeoutSeconds = 10 // 10s
 	PythonTryImportScript         = `import sys
-import imp
-file, pathName, description = imp.find_module("%s")
-if pathName:
- 	print pathName
-	sys.exit(0)
-else:
-	sys.exit(0)
+import importlib.util
+try:
+    spec = importlib.util.find_spec(sys.argv[1])
+except ImportError:
+    spec = None
+if spec is None:
+    sys.exit(0)
+if spec.submodule_search_locations:
+    # A package or namespace package
+    for path in spec.submodule_search_locations:
+        print(path)
+elif spec.origin and spec.origin not in ("built-in", "frozen", "namespace"):
+    print(spec.origin)
 `
 )
 
@@ -46,7 +58,7 @@ func NewPythonFinder() PythonFinder {
 	return PythonFinder{}
 }
 
-func (this PythonFinder) Find(ws *workspace.Workspace, params map[string]interface{}) ([]string, error) {
+func (this PythonFinder) Find(ws *workspace.Workspace, ctx *FindContext, params map[string]interface{}) ([]string, error) {
 	_module, ok := params[PythonFinderParamModule]
 	if !ok {
 		return nil, errors.New("Require module parameter")
@@ -63,34 +75,58 @@ func (this PythonFinder) Find(ws *workspace.Workspace, params map[string]interfa
 			return nil, errors.New("Invalid value of parent parameter")
 		}
 	}
-	// Create and run the python command
-	ctx, cancel := context.WithTimeout(context.Background(), PythonTryImportTimeoutSeconds*time.Second)
+	interpreter := ""
+	_interpreter, ok := params[PythonFinderParamInterpreter]
+	if ok {
+		interpreter, ok = _interpreter.(string)
+		if !ok {
+			return nil, errors.New("Invalid value of interpreter parameter")
+		}
+	}
+	if interpreter == "" {
+		interpreter = getDefaultPythonInterpreter()
+	}
+	// Create and run the python command, the script is written by stdin and the module is passed as argument
+	cmdCtx, cancel := context.WithTimeout(context.Background(), PythonTryImportTimeoutSeconds*time.Second)
 	defer cancel()
-	cmd := exec.CommandContext(ctx, "python")
-	// Write the code by stdin
-	writer, err := cmd.StdinPipe()
+	cmd := exec.CommandContext(cmdCtx, interpreter, "-", module)
+	cmd.Stdin = strings.NewReader(PythonTryImportScript)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	rtn, err := cmd.Output()
 	if err != nil {
-		return nil, err
+		return nil, errors.New(fmt.Sprintf("Failed to run python interpreter [%s], error: %s, stderr: %s", interpreter, err, strings.TrimSpace(stderr.String())))
 	}
-	go func() {
-		writer.Write([]byte(fmt.Sprintf(PythonTryImportScript, module)))
-		writer.Close()
-	}()
-	// Start the command
-	rtn, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, err
+	// Get the output as the paths
+	var paths []string
+	for _, line := range strings.Split(string(rtn), "\n") {
+		path := strings.Trim(line, " \t\r\n")
+		if path == "" {
+			continue
+		}
+		// Get with parent
+		for i := 0; i < parent; i++ {
+			path = filepath.Dir(path)
+		}
+		paths = appendUniquePath(paths, path)
 	}
-	// Get the output as the path
-	path := strings.Trim(string(rtn), "\t\r\n")
-	if path == "" {
-		// Path not found
-		return nil, nil
+	// Done
+	return paths, nil
+}
+
+// Get the default python interpreter
+func getDefaultPythonInterpreter() string {
+	if interpreter := os.Getenv("PYTHON"); interpreter != "" {
+		return interpreter
 	}
-	// Get with parent
-	for i := 0; i < parent; i++ {
-		path = filepath.Dir(path)
+	if venv := os.Getenv("VIRTUAL_ENV"); venv != "" {
+		interpreter := filepath.Join(venv, "bin", "python")
+		if _, err := os.Stat(interpreter); err == nil {
+			return interpreter
+		}
 	}
-	// Done
-	return []string{path}, nil
+	if interpreter, err := exec.LookPath("python3"); err == nil {
+		return interpreter
+	}
+	return "python"
 }
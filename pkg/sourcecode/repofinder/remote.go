@@ -0,0 +1,148 @@
+// Author: lipixun
+// Created Time : 一 12/26 16:45:03 2016
+//
+// File Name: remote.go
+// Description:
+//	Find repository by matching the git remote url or the uri of repository spec
+//	Optional parameters:
+// 		uri 			string 		The repository uri to match, use the referenced uri if not specified
+// 		paths 			[]string 	The additional search roots (glob pattern is supported)
+//
+// 	The candidate directories are the search roots, the sub directories of the search roots
+// 	and the sibling directories of current repository
+//
+
+package repofinder
+
+import (
+	"errors"
+	git "github.com/libgit2/git2go"
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"github.com/ops-openlight/openlight/pkg/workspace"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	FinderTypeRemote = "remote"
+
+	RemoteFinderParamUri = "uri"
+)
+
+var (
+	// The scheme and user info prefix of git remote url, e.g. https://user@ or ssh://git@
+	GitRemoteUrlPrefixRegexp = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9+.-]*://)?([^@/]+@)?`)
+)
+
+type RemoteFinder struct{}
+
+func NewRemoteFinder() RemoteFinder {
+	return RemoteFinder{}
+}
+
+func (this RemoteFinder) Find(ws *workspace.Workspace, ctx *FindContext, params map[string]interface{}) ([]string, error) {
+	var uri string
+	if ctx != nil {
+		uri = ctx.Uri
+	}
+	if _uri, ok := params[RemoteFinderParamUri]; ok {
+		uri, ok = _uri.(string)
+		if !ok {
+			return nil, errors.New("Invalid value of uri parameter")
+		}
+	}
+	if uri == "" {
+		return nil, errors.New("Require uri parameter")
+	}
+	uri = normalizeRepositoryUrl(uri)
+	// Get the candidate directories
+	roots, err := getSearchRoots(ws, params)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []string
+	for _, root := range roots {
+		candidates = appendUniquePath(candidates, root)
+		if matches, err := filepath.Glob(filepath.Join(root, "*")); err == nil {
+			for _, match := range matches {
+				candidates = appendUniquePath(candidates, match)
+			}
+		}
+	}
+	if ctx != nil && ctx.RepositoryPath != "" {
+		if matches, err := filepath.Glob(filepath.Join(filepath.Dir(ctx.RepositoryPath), "*")); err == nil {
+			for _, match := range matches {
+				if match != ctx.RepositoryPath {
+					candidates = appendUniquePath(candidates, match)
+				}
+			}
+		}
+	}
+	// Match the candidates
+	var paths []string
+	for _, candidate := range candidates {
+		if isDir(candidate) && matchRepositoryUri(candidate, uri) {
+			paths = append(paths, candidate)
+		}
+	}
+	// Done
+	return paths, nil
+}
+
+// Check if the repository at path matches the (normalized) uri by the spec uri or the git remote urls
+func matchRepositoryUri(path, uri string) bool {
+	// Check the spec
+	if data, err := ioutil.ReadFile(filepath.Join(path, spec.SpecFileName)); err == nil {
+		var repoSpec spec.RepositorySpec
+		if err := yaml.Unmarshal(data, &repoSpec); err == nil && repoSpec.Uri != "" && normalizeRepositoryUrl(repoSpec.Uri) == uri {
+			return true
+		}
+	}
+	// Check the git remotes, the .git is a file for worktrees and submodules
+	if _, err := os.Stat(filepath.Join(path, ".git")); err != nil {
+		return false
+	}
+	gitRepo, err := git.OpenRepository(path)
+	if err != nil {
+		return false
+	}
+	defer gitRepo.Free()
+	names, err := gitRepo.Remotes.List()
+	if err != nil {
+		return false
+	}
+	for _, name := range names {
+		remote, err := gitRepo.Remotes.Lookup(name)
+		if err != nil {
+			continue
+		}
+		url := remote.Url()
+		remote.Free()
+		if normalizeRepositoryUrl(url) == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize the repository url, e.g. https://github.com/a/b.git, git@github.com:a/b and github.com/a/b are all normalized to github.com/a/b
+// Only the host is lowercased since the path is case sensitive on most git servers
+func normalizeRepositoryUrl(url string) string {
+	hasScheme := strings.Contains(url, "://")
+	url = GitRemoteUrlPrefixRegexp.ReplaceAllString(url, "")
+	if !hasScheme {
+		// The scp-like syntax, e.g. github.com:a/b
+		if index := strings.Index(url, ":"); index != -1 && !strings.Contains(url[:index], "/") {
+			url = url[:index] + "/" + url[index+1:]
+		}
+	}
+	url = strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+	if index := strings.Index(url, "/"); index != -1 {
+		return strings.ToLower(url[:index]) + url[index:]
+	}
+	return strings.ToLower(url)
+}
@@ -0,0 +1,64 @@
+// Author: lipixun
+// Created Time : 一 12/26 16:20:41 2016
+//
+// File Name: sibling.go
+// Description:
+//	Find repository in the sibling directories of current repository
+//	Optional parameters:
+// 		name 			string 	The directory name (glob pattern is supported), use the last element of the referenced uri if not specified
+//
+
+package repofinder
+
+import (
+	"errors"
+	"github.com/ops-openlight/openlight/pkg/workspace"
+	"path/filepath"
+)
+
+const (
+	FinderTypeSibling = "sibling"
+
+	SiblingFinderParamName = "name"
+)
+
+type SiblingFinder struct{}
+
+func NewSiblingFinder() SiblingFinder {
+	return SiblingFinder{}
+}
+
+func (this SiblingFinder) Find(ws *workspace.Workspace, ctx *FindContext, params map[string]interface{}) ([]string, error) {
+	name := ctx.GetDefaultName()
+	if _name, ok := params[SiblingFinderParamName]; ok {
+		name, ok = _name.(string)
+		if !ok {
+			return nil, errors.New("Invalid value of name parameter")
+		}
+	}
+	if name == "" {
+		return nil, errors.New("Require name parameter")
+	}
+	// Get the current repository path
+	var repoPath string
+	if ctx != nil && ctx.RepositoryPath != "" {
+		repoPath = ctx.RepositoryPath
+	} else if ws != nil && ws.Dir.Project != nil {
+		repoPath = ws.Dir.Project.RootPath()
+	} else {
+		return nil, errors.New("Current repository path not found")
+	}
+	// Find the sibling
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(repoPath), name))
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, match := range matches {
+		if match != repoPath && isDir(match) {
+			paths = append(paths, match)
+		}
+	}
+	// Done
+	return paths, nil
+}
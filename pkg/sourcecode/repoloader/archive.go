@@ -0,0 +1,209 @@
+// Author: lipixun
+// Created Time : 四 12/29 16:02:51 2016
+//
+// File Name: archive.go
+// Description:
+//	The archive repository loader
+//	The archive (.tar.gz, .tgz, .tar or .zip) is downloaded (or opened from local path) and extracted into the cache
+//	The metadata is loaded as the plain directory, the commit defaults to the checksum of the archive
+package repoloader
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/ops-openlight/openlight/pkg/log"
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"github.com/ops-openlight/openlight/pkg/util"
+	"github.com/ops-openlight/openlight/pkg/workspace"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	RepositoryTypeArchive = spec.RepositoryTypeArchive
+
+	ArchiveFormatTarGz = "tar.gz"
+	ArchiveFormatTar   = "tar"
+	ArchiveFormatZip   = "zip"
+)
+
+var (
+	// The timeout of downloading an archive
+	ArchiveDownloadTimeout = 10 * time.Minute
+)
+
+type ArchiveLoader struct {
+}
+
+func NewArchiveLoader() Loader {
+	return ArchiveLoader{}
+}
+
+func (this ArchiveLoader) Load(remote string, options LoadOptions, ws *workspace.Workspace) (*spec.Repository, error) {
+	if options.Branch != "" || options.Commit != "" || options.Tag != "" {
+		ws.Logger.LeveledPrintf(log.LevelWarn, "Branch, commit and tag will be ignored when load from archive for repository [%s]\n", remote)
+	}
+	// The local directory (e.g. found by finder) is loaded directly
+	if info, err := os.Stat(remote); err == nil && info.IsDir() {
+		return DirLoader{}.Load(remote, options, ws)
+	}
+	format := getArchiveFormat(remote)
+	if format == "" {
+		return nil, errors.New(fmt.Sprintf("Unknown archive format of [%s]", remote))
+	}
+	cachePath, err := ws.Dir.User.GetPath(filepath.Join("sourcecode", "archives"))
+	if err != nil {
+		return nil, err
+	}
+	isRemote := strings.HasPrefix(remote, "http://") || strings.HasPrefix(remote, "https://")
+	expectedChecksum := strings.ToLower(strings.TrimPrefix(options.Checksum, spec.ChecksumPrefixSha256))
+	if expectedChecksum == "" {
+		if isRemote {
+			return nil, errors.New(fmt.Sprintf("Checksum is required to download archive [%s]", remote))
+		}
+		ws.Logger.LeveledPrintf(log.LevelWarn, "No checksum defined for archive [%s]\n", remote)
+	} else if isDir(filepath.Join(cachePath, expectedChecksum)) {
+		// Extracted before
+		ws.Logger.LeveledPrintf(log.LevelDebug, "Use cached archive [%s] of [%s]\n", expectedChecksum, remote)
+		return loadFromDirectory(getArchiveRoot(filepath.Join(cachePath, expectedChecksum)), remote, options.Subdir, spec.RepositoryMetadata{Commit: spec.ChecksumPrefixSha256 + expectedChecksum}, options.Root)
+	}
+	// Get the archive file
+	filename := remote
+	if isRemote {
+		filename, err = downloadArchive(remote, cachePath)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Failed to download archive [%s], error: %s", remote, err))
+		}
+		defer os.Remove(filename)
+	}
+	// Verify the checksum
+	checksum, err := getFileSha256(filename)
+	if err != nil {
+		return nil, err
+	}
+	if expectedChecksum != "" && checksum != expectedChecksum {
+		return nil, errors.New(fmt.Sprintf("Mismatch checksum of archive [%s]. Expected [%s] Actually [%s]", remote, expectedChecksum, checksum))
+	}
+	// Extract the archive into the cache
+	extractPath := filepath.Join(cachePath, checksum)
+	if !isDir(extractPath) {
+		if err := extractArchive(filename, format, extractPath); err != nil {
+			return nil, errors.New(fmt.Sprintf("Failed to extract archive [%s], error: %s", remote, err))
+		}
+	}
+	return loadFromDirectory(getArchiveRoot(extractPath), remote, options.Subdir, spec.RepositoryMetadata{Commit: spec.ChecksumPrefixSha256 + checksum}, options.Root)
+}
+
+// Get the archive format by the file name, returns empty string if unknown
+func getArchiveFormat(name string) string {
+	name = strings.ToLower(name)
+	if idx := strings.IndexAny(name, "?#"); idx != -1 && strings.Contains(name, "://") {
+		// Strip the query of url
+		name = name[:idx]
+	}
+	if strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz") {
+		return ArchiveFormatTarGz
+	} else if strings.HasSuffix(name, ".tar") {
+		return ArchiveFormatTar
+	} else if strings.HasSuffix(name, ".zip") {
+		return ArchiveFormatZip
+	}
+	return ""
+}
+
+// Download the archive into the directory, returns the downloaded file path
+func downloadArchive(url, p string) (string, error) {
+	client := &http.Client{Timeout: ArchiveDownloadTimeout}
+	rsp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return "", errors.New(fmt.Sprintf("Unexpected status [%s]", rsp.Status))
+	}
+	file, err := ioutil.TempFile(p, "download-")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := io.Copy(file, rsp.Body); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// Get the sha256 (in hex) of the file
+func getFileSha256(filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Extract the archive to the path, the path is created only if the archive is extracted successfully
+func extractArchive(filename, format, p string) error {
+	tempPath, err := ioutil.TempDir(filepath.Dir(p), "extract-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempPath)
+	if format == ArchiveFormatZip {
+		err = util.ZipExtract(filename, tempPath)
+	} else {
+		var file *os.File
+		file, err = os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		var reader io.Reader = file
+		if format == ArchiveFormatTarGz {
+			gzipReader, err := gzip.NewReader(file)
+			if err != nil {
+				return err
+			}
+			defer gzipReader.Close()
+			reader = gzipReader
+		}
+		err = util.TarExtract(tar.NewReader(reader), tempPath)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tempPath, p)
+}
+
+// Get the root of the extracted archive, the single top level directory (e.g. repo-1.0/) is used as root if no spec file found
+func getArchiveRoot(p string) string {
+	if _, err := os.Stat(filepath.Join(p, spec.SpecFileName)); err == nil {
+		return p
+	}
+	infos, err := ioutil.ReadDir(p)
+	if err != nil || len(infos) != 1 || !infos[0].IsDir() {
+		return p
+	}
+	return filepath.Join(p, infos[0].Name())
+}
+
+// Check if the path is a directory
+func isDir(p string) bool {
+	info, err := os.Stat(p)
+	return err == nil && info.IsDir()
+}
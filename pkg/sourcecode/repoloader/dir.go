@@ -0,0 +1,114 @@
+// Author: lipixun
+// Created Time : 四 12/29 15:21:08 2016
+//
+// File Name: dir.go
+// Description:
+//	The plain directory repository loader
+//	The metadata is loaded from the metadata file in the directory, and overwritten by the environment variables for the root repository:
+//		OP_REPOSITORY_BRANCH, OP_REPOSITORY_COMMIT, OP_REPOSITORY_MESSAGE, OP_REPOSITORY_DESCRIBE
+package repoloader
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ops-openlight/openlight/pkg/log"
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"github.com/ops-openlight/openlight/pkg/workspace"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const (
+	RepositoryTypeDir = spec.RepositoryTypeDir
+
+	DirMetadataEnvPrefix = "OP_REPOSITORY_"
+)
+
+type DirLoader struct {
+}
+
+func NewDirLoader() Loader {
+	return DirLoader{}
+}
+
+func (this DirLoader) Load(remote string, options LoadOptions, ws *workspace.Workspace) (*spec.Repository, error) {
+	if options.Branch != "" || options.Commit != "" || options.Tag != "" {
+		ws.Logger.LeveledPrintf(log.LevelWarn, "Branch, commit and tag will be ignored when load from directory for repository [%s]\n", remote)
+	}
+	p, err := filepath.Abs(remote)
+	if err != nil {
+		return nil, err
+	}
+	return loadFromDirectory(p, remote, options.Subdir, spec.RepositoryMetadata{}, options.Root)
+}
+
+// Create repository from a plain directory
+// Parameters:
+// 	p 			The directory path
+// 	source 		The source of the repository
+// 	subdir 		The directory of the spec file relative to the directory
+// 	metadata 	The default metadata, overwritten by the metadata file (and environment variables)
+// 	overwriteByEnv 	Overwrite the metadata by environment variables, only the root repository should be overwritten
+func loadFromDirectory(p, source, subdir string, metadata spec.RepositoryMetadata, overwriteByEnv bool) (*spec.Repository, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, errors.New(fmt.Sprintf("Repository path [%s] is not a directory", p))
+	}
+	localPath := filepath.Join(p, subdir)
+	// Load metadata
+	if err := loadMetadataFromFile(filepath.Join(localPath, spec.MetadataFileName), &metadata); err != nil {
+		return nil, errors.New(fmt.Sprintf("Failed to load repository metadata file, error: %s", err))
+	}
+	if overwriteByEnv {
+		for name, value := range map[string]*string{
+			"BRANCH":   &metadata.Branch,
+			"COMMIT":   &metadata.Commit,
+			"MESSAGE":  &metadata.Message,
+			"DESCRIBE": &metadata.Describe,
+		} {
+			if env := os.Getenv(DirMetadataEnvPrefix + name); env != "" {
+				*value = env
+			}
+		}
+	}
+	if metadata.Describe == "" {
+		metadata.Describe = metadata.Commit
+	}
+	// Load spec
+	repoSpec, err := LoadRepositorySpecFromFile(filepath.Join(localPath, spec.SpecFileName))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Failed to load repository spec file, error: %s", err))
+	}
+	if repoSpec.Uri == "" {
+		return nil, errors.New("Invalid repository spec, uri is required")
+	}
+	// Create the repository
+	repo := &spec.Repository{
+		Uri:      repoSpec.Uri,
+		Source:   source,
+		Metadata: metadata,
+		Spec:     repoSpec,
+		Local: spec.RepositoryLocalInfo{
+			Path: localPath,
+		},
+	}
+	// Done
+	return repo, nil
+}
+
+// Load the metadata from file, the file is optional
+func loadMetadataFromFile(filename string, metadata *spec.RepositoryMetadata) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return yaml.UnmarshalStrict(data, metadata)
+}
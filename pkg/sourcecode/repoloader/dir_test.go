@@ -0,0 +1,54 @@
+// Author: lipixun
+// Created Time : 四 12/29 22:31:06 2016
+//
+// File Name: dir_test.go
+// Description:
+//
+package repoloader
+
+import (
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFromDirectory(t *testing.T) {
+	path, err := ioutil.TempDir("", "dirloader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+	if err := ioutil.WriteFile(filepath.Join(path, spec.SpecFileName), []byte("uri: github.com/ops-openlight/test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(path, spec.MetadataFileName), []byte("branch: master\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range map[string]string{
+		DirMetadataEnvPrefix + "BRANCH": "release",
+		DirMetadataEnvPrefix + "COMMIT": "1a2b3c4",
+	} {
+		defer os.Setenv(name, os.Getenv(name))
+		os.Setenv(name, value)
+	}
+	for _, c := range []struct {
+		Root     bool
+		Branch   string
+		Commit   string
+		Describe string
+	}{
+		{true, "release", "1a2b3c4", "1a2b3c4"},
+		// The environment variables are not applied to the referenced repository (e.g. the archive)
+		{false, "master", "sha256:abc", "sha256:abc"},
+	} {
+		repo, err := loadFromDirectory(path, path, "", spec.RepositoryMetadata{Commit: "sha256:abc"}, c.Root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if repo.Metadata.Branch != c.Branch || repo.Metadata.Commit != c.Commit || repo.Metadata.Describe != c.Describe {
+			t.Errorf("Mismatch metadata of root [%v]. Expected [%s %s %s] Actually [%s %s %s]", c.Root, c.Branch, c.Commit, c.Describe, repo.Metadata.Branch, repo.Metadata.Commit, repo.Metadata.Describe)
+		}
+	}
+}
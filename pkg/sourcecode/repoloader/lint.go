@@ -0,0 +1,151 @@
+// Author: lipixun
+// Created Time : 三 12/28 11:05:32 2016
+//
+// File Name: lint.go
+// Description:
+//	Lint the repository spec file
+package repoloader
+
+import (
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// The line prefix of yaml error message, e.g. "line 12: field nopull not found in type spec.DockerBuildSpec"
+	YamlErrorLineRegularExp = regexp.MustCompile("^(yaml: )?line (\\d+): (.*)$")
+)
+
+// Lint the repository spec file, both the decoding errors and the validation errors are returned with positions
+// NOTE: The positions are in the spec file itself, the errors of the included files and templates may have no position
+func LintRepositorySpecFile(filename string) ([]*spec.SpecError, error) {
+	if _, err := os.Stat(filename); err != nil {
+		return nil, err
+	}
+	// Read the spec file, the lines are kept
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := readSpecFileWithVars(filename, make(map[string]string))
+	if err != nil {
+		return []*spec.SpecError{newYamlSpecError(err.Error())}, nil
+	}
+	// Decode the spec file itself strictly, only the errors which still exist after the vars are substituted are reported
+	if errs := decodeRepositorySpecStrictly(data); len(errs) > 0 {
+		substitutedData, err := yaml.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		if errs = mergeSpecDecodingErrors(errs, decodeRepositorySpecStrictly(substitutedData)); len(errs) > 0 {
+			return errs, nil
+		}
+	}
+	// Decode the preprocessed spec strictly
+	preprocessedData, err := PreprocessRepositorySpecFile(filename)
+	if err != nil {
+		return []*spec.SpecError{{Message: err.Error()}}, nil
+	}
+	var repoSpec spec.RepositorySpec
+	if err := yaml.UnmarshalStrict(preprocessedData, &repoSpec); err != nil {
+		return []*spec.SpecError{{Message: err.Error()}}, nil
+	}
+	// Validate
+	errs := repoSpec.Validate()
+	if len(errs) > 0 {
+		var root yamlv3.Node
+		if err := yamlv3.Unmarshal(data, &root); err == nil {
+			for _, err := range errs {
+				err.Line, err.Column = locateYamlNode(&root, err.Path)
+			}
+		}
+	}
+	// Done
+	return errs, nil
+}
+
+// Decode the spec data strictly, returns the decoding errors
+func decodeRepositorySpecStrictly(data []byte) []*spec.SpecError {
+	var repoSpec spec.RepositorySpec
+	err := yaml.UnmarshalStrict(data, &repoSpec)
+	if err == nil {
+		return nil
+	}
+	typeError, ok := err.(*yaml.TypeError)
+	if !ok {
+		// Syntax error
+		return []*spec.SpecError{newYamlSpecError(err.Error())}
+	}
+	var errs []*spec.SpecError
+	for _, message := range typeError.Errors {
+		errs = append(errs, newYamlSpecError(message))
+	}
+	return errs
+}
+
+// Merge the decoding errors of the raw spec and the substituted spec
+// The errors of the substituted spec are returned, with the position of the same error of the raw spec if any
+func mergeSpecDecodingErrors(rawErrs, substitutedErrs []*spec.SpecError) []*spec.SpecError {
+	var errs []*spec.SpecError
+	used := make(map[int]bool)
+	for _, substitutedErr := range substitutedErrs {
+		located := &spec.SpecError{Message: substitutedErr.Message}
+		for i, rawErr := range rawErrs {
+			if !used[i] && rawErr.Message == substitutedErr.Message {
+				located.Line = rawErr.Line
+				used[i] = true
+				break
+			}
+		}
+		errs = append(errs, located)
+	}
+	return errs
+}
+
+// Create spec error from yaml error message
+func newYamlSpecError(message string) *spec.SpecError {
+	matches := YamlErrorLineRegularExp.FindStringSubmatch(message)
+	if matches == nil {
+		return &spec.SpecError{Message: message}
+	}
+	line, _ := strconv.Atoi(matches[2])
+	return &spec.SpecError{Line: line, Message: matches[3]}
+}
+
+// Locate the node by path, the position of the deepest existing node is returned if the path is not found
+func locateYamlNode(root *yamlv3.Node, path []string) (int, int) {
+	node := root
+	if node.Kind == yamlv3.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	line, column := node.Line, node.Column
+	for _, name := range path {
+		var next *yamlv3.Node
+		switch node.Kind {
+		case yamlv3.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == name {
+					// Use the position of the key
+					line, column = node.Content[i].Line, node.Content[i].Column
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yamlv3.SequenceNode:
+			if index, err := strconv.Atoi(name); err == nil && index >= 0 && index < len(node.Content) {
+				next = node.Content[index]
+				line, column = next.Line, next.Column
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return line, column
+}
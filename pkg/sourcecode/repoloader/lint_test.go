@@ -0,0 +1,75 @@
+// Author: lipixun
+// Created Time : 四 12/29 23:02:36 2016
+//
+// File Name: lint_test.go
+// Description:
+//
+package repoloader
+
+import (
+	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLintRepositorySpecFile(t *testing.T) {
+	path, err := ioutil.TempDir("", "lint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+	type lintError struct {
+		Line    int
+		Column  int
+		Message string
+	}
+	for _, c := range []struct {
+		Data     string
+		Expected []lintError
+	}{
+		{"uri: github.com/ops-openlight/test\ntargets:\n  x:\n    build:\n      type: golang\n      golang:\n        package: p\n", nil},
+		// The unknown field, only the line is known
+		{"uri: github.com/ops-openlight/test\ntargets:\n  x:\n    build:\n      type: docker\n      docker:\n        image: a\n        nopul: true\n", []lintError{
+			{8, 0, "field nopul not found in type spec.DockerBuildSpec"},
+		}},
+		// The syntax error
+		{"uri: github.com/ops-openlight/test\ntargets:\n  x: [\n", []lintError{
+			{3, 0, "did not find expected node content"},
+		}},
+		// The validation errors are located at the key of the field
+		{"uri: github.com/ops-openlight/test\ntargets:\n  x:\n    build:\n      type: golang\n      docker:\n        image: a\n", []lintError{
+			{4, 5, "targets.x.build: Build section [golang] is required by build type [golang]"},
+			{6, 7, "targets.x.build.docker: Build section [docker] is defined but build type is [golang]"},
+		}},
+		// The field not found is located at the deepest existing node
+		{"targets:\n  x:\n    build:\n      type: golang\n      golang:\n        package: p\n", []lintError{
+			{1, 1, "uri: Require uri"},
+		}},
+		// The preprocessing error has no position
+		{"uri: github.com/ops-openlight/test\ntargets:\n  x:\n    template: unknown\n", []lintError{
+			{0, 0, "Template [unknown] of target [x] not found"},
+		}},
+	} {
+		filename := filepath.Join(path, spec.SpecFileName)
+		if err := ioutil.WriteFile(filename, []byte(c.Data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		errs, err := LintRepositorySpecFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(errs) != len(c.Expected) {
+			t.Errorf("Mismatch errors of spec [%s]. Expected %v Actually %v", c.Data, c.Expected, errs)
+			continue
+		}
+		for i, expected := range c.Expected {
+			actual := lintError{errs[i].Line, errs[i].Column, errs[i].Error()}
+			if actual.Line != expected.Line || actual.Column != expected.Column || !strings.Contains(actual.Message, expected.Message) {
+				t.Errorf("Mismatch error of spec [%s]. Expected %v Actually %v", c.Data, expected, actual)
+			}
+		}
+	}
+}
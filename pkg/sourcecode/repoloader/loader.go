@@ -13,7 +13,9 @@ import (
 
 var (
 	loaders map[string]Loader = map[string]Loader{
-		RepositoryTypeGit: NewGitLoader(),
+		RepositoryTypeGit:     NewGitLoader(),
+		RepositoryTypeDir:     NewDirLoader(),
+		RepositoryTypeArchive: NewArchiveLoader(),
 	}
 )
 
@@ -22,8 +24,16 @@ type Loader interface {
 }
 
 type LoadOptions struct {
-	Branch string
-	Commit string
+	Branch     string
+	Commit     string
+	Tag        string // The tag or semver range of tags
+	Subdir     string // The directory of the spec file relative to the repository root
+	Checksum   string // The checksum of the archive, format: sha256:<hex>
+	Submodules bool   // Init and update the git submodules recursively
+	LFS        bool   // Fetch the git LFS objects
+	LFSStore   string // The local LFS store (shared by all repositories), empty means the default store of each repository
+	Readonly   bool   // The local repository must not be changed (e.g. the checkout found by finder), submodules and LFS objects are not materialized
+	Root       bool   // The repository is loaded directly (not referenced by other repositories), the metadata could be overwritten by environment variables
 }
 
 func GetLoader(t string) Loader {
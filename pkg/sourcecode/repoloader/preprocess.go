@@ -0,0 +1,317 @@
+// Author: lipixun
+// Created Time : 三 12/28 15:20:44 2016
+//
+// File Name: preprocess.go
+// Description:
+//	Preprocess the repository spec file before decoding:
+//		1. Substitute ${var} in the string values by the vars section (and the vars of the including files), and ${env:NAME} by the environment variable
+//		   The undeclared vars and unset environment variables (e.g. ${HOME} in shell commands) are kept as is. Use $${var} or $${env:NAME} to escape
+//		2. Merge the included files, only references, overrides, templates, targets and vars are allowed in included files
+//		   The include of the preprocessed spec is replaced by all included files (sorted, relative to the repository root)
+//		3. Merge the target template into the target, the fields of the target override the template
+package repoloader
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	SpecKeyInclude   = "include"
+	SpecKeyVars      = "vars"
+	SpecKeyTemplates = "templates"
+	SpecKeyTargets   = "targets"
+	SpecKeyTemplate  = "template"
+
+	SpecVarEnvPrefix = "env:"
+)
+
+var (
+	SpecVariableRegularExp = regexp.MustCompile("\\$?\\$\\{((?:env:)?[a-zA-Z_][a-zA-Z0-9_]*)\\}")
+
+	// The top level keys allowed in included files
+	SpecIncludableKeys = []string{"references", "overrides", SpecKeyTemplates, SpecKeyTargets, SpecKeyVars}
+)
+
+// Preprocess the repository spec file
+// Returns:
+// 	The preprocessed spec data to decode
+func PreprocessRepositorySpecFile(filename string) ([]byte, error) {
+	filename, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	included := make(map[string]bool)
+	doc, err := readSpecDocument(filename, filepath.Dir(filename), make(map[string]string), make(map[string]bool), included)
+	if err != nil {
+		return nil, err
+	}
+	if len(included) > 0 {
+		var includedFiles []string
+		for file := range included {
+			includedFiles = append(includedFiles, file)
+		}
+		sort.Strings(includedFiles)
+		doc[SpecKeyInclude] = includedFiles
+	}
+	if err := applyTargetTemplates(doc); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(doc)
+}
+
+// Read the spec file with vars substituted
+// Parameters:
+// 	filename 		The spec file name
+// 	vars 			The vars defined by the including files, which has higher priority than the vars in this file
+func readSpecFileWithVars(filename string, vars map[string]string) (map[interface{}]interface{}, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	doc := make(map[interface{}]interface{})
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	// Get the vars
+	if _vars := doc[SpecKeyVars]; _vars != nil {
+		fileVars, ok := _vars.(map[interface{}]interface{})
+		if !ok {
+			return nil, errors.New("Invalid vars, must be a map")
+		}
+		for name, value := range fileVars {
+			if _, ok := vars[fmt.Sprint(name)]; !ok {
+				vars[fmt.Sprint(name)] = fmt.Sprint(value)
+			}
+		}
+	}
+	// Substitute
+	for key, value := range doc {
+		if key != SpecKeyVars {
+			doc[key] = substituteSpecVars(value, vars)
+		}
+	}
+	return doc, nil
+}
+
+// Substitute ${var} in the string values recursively
+// The value which is exactly one ${var} is decoded as a yaml scalar (e.g. the number), the others are always strings
+func substituteSpecVars(value interface{}, vars map[string]string) interface{} {
+	switch v := value.(type) {
+	case string:
+		if match := SpecVariableRegularExp.FindStringSubmatch(v); match != nil && match[0] == v && !strings.HasPrefix(v, "$$") {
+			if varValue, ok := lookupSpecVar(match[1], vars); ok {
+				var scalar interface{}
+				if err := yaml.Unmarshal([]byte(varValue), &scalar); err == nil {
+					switch scalar.(type) {
+					case bool, int, int64, uint64, float64:
+						return scalar
+					}
+				}
+				return varValue
+			}
+		}
+		return SpecVariableRegularExp.ReplaceAllStringFunc(v, func(match string) string {
+			if strings.HasPrefix(match, "$$") {
+				// Escaped
+				return match[1:]
+			}
+			varValue, ok := lookupSpecVar(match[2:len(match)-1], vars)
+			if !ok {
+				// Not declared, keep it as is
+				return match
+			}
+			return varValue
+		})
+	case map[interface{}]interface{}:
+		for key, item := range v {
+			v[key] = substituteSpecVars(item, vars)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = substituteSpecVars(item, vars)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// Lookup the value of the var, the name with env: prefix is looked up in the environment variables
+func lookupSpecVar(name string, vars map[string]string) (string, bool) {
+	if strings.HasPrefix(name, SpecVarEnvPrefix) {
+		return os.LookupEnv(name[len(SpecVarEnvPrefix):])
+	}
+	value, ok := vars[name]
+	return value, ok
+}
+
+// Read the spec document with the included files merged
+// Parameters:
+// 	filename 		The absolute spec file name
+// 	rootPath 		The repository root path, the included files must be in it
+// 	vars 			The vars defined by the including files
+// 	visited 		The files in the including path, used to detect include loop
+// 	included 		The included files (relative to the root path, in slash form)
+func readSpecDocument(filename, rootPath string, vars map[string]string, visited map[string]bool, included map[string]bool) (map[interface{}]interface{}, error) {
+	if visited[filename] {
+		return nil, errors.New(fmt.Sprintf("Include loop found on file [%s]", filename))
+	}
+	visited[filename] = true
+	defer delete(visited, filename)
+	// Read the file
+	doc, err := readSpecFileWithVars(filename, vars)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Failed to read spec file [%s], error: %s", filename, err))
+	}
+	// Merge the included files
+	_includes, ok := doc[SpecKeyInclude]
+	if !ok {
+		return doc, nil
+	}
+	includes, ok := _includes.([]interface{})
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Invalid include of spec file [%s], must be a list", filename))
+	}
+	for _, _include := range includes {
+		include, ok := _include.(string)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("Invalid include [%v] of spec file [%s]", _include, filename))
+		}
+		pattern := include
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(filename), pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid include [%s] of spec file [%s], error: %s", include, filename, err))
+		}
+		if len(matches) == 0 {
+			return nil, errors.New(fmt.Sprintf("Included file [%s] of spec file [%s] not found", include, filename))
+		}
+		for _, match := range matches {
+			rel, err := filepath.Rel(rootPath, match)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return nil, errors.New(fmt.Sprintf("Included file [%s] is out of the repository", match))
+			}
+			included[filepath.ToSlash(rel)] = true
+			includedDoc, err := readSpecDocument(match, rootPath, copySpecVars(vars), visited, included)
+			if err != nil {
+				return nil, err
+			}
+			if err := mergeIncludedSpecDocument(doc, includedDoc, match); err != nil {
+				return nil, err
+			}
+		}
+	}
+	delete(doc, SpecKeyInclude)
+	// Done
+	return doc, nil
+}
+
+func copySpecVars(vars map[string]string) map[string]string {
+	copied := make(map[string]string)
+	for name, value := range vars {
+		copied[name] = value
+	}
+	return copied
+}
+
+// Merge the included document into the document, the duplicated keys are not allowed
+func mergeIncludedSpecDocument(doc, includedDoc map[interface{}]interface{}, filename string) error {
+	for key, value := range includedDoc {
+		allowed := false
+		for _, includableKey := range SpecIncludableKeys {
+			if key == includableKey {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return errors.New(fmt.Sprintf("Key [%v] is not allowed in included file [%s]", key, filename))
+		}
+		values, ok := value.(map[interface{}]interface{})
+		if !ok {
+			if value == nil {
+				continue
+			}
+			return errors.New(fmt.Sprintf("Invalid [%v] of included file [%s], must be a map", key, filename))
+		}
+		if doc[key] == nil {
+			doc[key] = make(map[interface{}]interface{})
+		}
+		existed, ok := doc[key].(map[interface{}]interface{})
+		if !ok {
+			return errors.New(fmt.Sprintf("Invalid [%v] of spec file, must be a map", key))
+		}
+		for name, v := range values {
+			if _, ok := existed[name]; ok {
+				if key == SpecKeyVars {
+					// The vars of including file have higher priority
+					continue
+				}
+				return errors.New(fmt.Sprintf("Duplicated [%v] [%v] in included file [%s]", key, name, filename))
+			}
+			existed[name] = v
+		}
+	}
+	return nil
+}
+
+// Merge the templates into the targets
+func applyTargetTemplates(doc map[interface{}]interface{}) error {
+	targets, _ := doc[SpecKeyTargets].(map[interface{}]interface{})
+	templates, _ := doc[SpecKeyTemplates].(map[interface{}]interface{})
+	for name, _target := range targets {
+		target, ok := _target.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		_template, ok := target[SpecKeyTemplate]
+		if !ok {
+			continue
+		}
+		templateName, ok := _template.(string)
+		if !ok {
+			return errors.New(fmt.Sprintf("Invalid template of target [%v]", name))
+		}
+		template, ok := templates[templateName]
+		if !ok {
+			return errors.New(fmt.Sprintf("Template [%s] of target [%v] not found", templateName, name))
+		}
+		targets[name] = mergeSpecValue(template, target)
+	}
+	return nil
+}
+
+// Merge the overlay into the base, the maps are merged recursively and the other values are replaced
+func mergeSpecValue(base, overlay interface{}) interface{} {
+	baseMap, ok := base.(map[interface{}]interface{})
+	if !ok {
+		return overlay
+	}
+	overlayMap, ok := overlay.(map[interface{}]interface{})
+	if !ok {
+		return overlay
+	}
+	merged := make(map[interface{}]interface{})
+	for key, value := range baseMap {
+		merged[key] = value
+	}
+	for key, value := range overlayMap {
+		if baseValue, ok := merged[key]; ok {
+			merged[key] = mergeSpecValue(baseValue, value)
+		} else {
+			merged[key] = value
+		}
+	}
+	return merged
+}
@@ -0,0 +1,115 @@
+// Author: lipixun
+// Created Time : 三 12/28 17:02:45 2016
+//
+// File Name: preprocess_test.go
+// Description:
+//
+package repoloader
+
+import (
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testPreprocessSpec = `uri: github.com/ops-openlight/test
+vars:
+  image: "registry:5000/api"  # The value with special chars
+  note: "a # b"
+  multiline: "line1\nline2"
+  port: "8080"
+targets:
+  api:
+    build:
+      type: shell
+      command: "cd ${HOME} && echo $PATH ${UNDECLARED} $${image} $${undeclared} $${env:OP_TEST_REGISTRY}"
+    image: ${image}
+    note: ${note}
+    multiline: "[${multiline}]"
+    port: ${port}
+    address: "localhost:${port}"
+    registry: "${env:OP_TEST_REGISTRY}/api"
+    replicas: ${env:OP_TEST_REPLICAS}
+    unset: ${env:OP_TEST_UNSET}
+`
+
+func TestPreprocessRepositorySpecFile(t *testing.T) {
+	for name, value := range map[string]string{"OP_TEST_REGISTRY": "registry:5000", "OP_TEST_REPLICAS": "3"} {
+		defer os.Setenv(name, os.Getenv(name))
+		os.Setenv(name, value)
+	}
+	os.Unsetenv("OP_TEST_UNSET")
+	path, err := ioutil.TempDir("", "preprocess")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+	filename := filepath.Join(path, "spec.yaml")
+	if err := ioutil.WriteFile(filename, []byte(testPreprocessSpec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := PreprocessRepositorySpecFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Targets map[string]map[string]interface{} `yaml:"targets"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	target := doc.Targets["api"]
+	for name, expected := range map[string]interface{}{
+		"build":     map[interface{}]interface{}{"type": "shell", "command": "cd ${HOME} && echo $PATH ${UNDECLARED} ${image} ${undeclared} ${env:OP_TEST_REGISTRY}"},
+		"image":     "registry:5000/api",
+		"note":      "a # b",
+		"multiline": "[line1\nline2]",
+		"port":      8080,
+		"address":   "localhost:8080",
+		"registry":  "registry:5000/api",
+		"replicas":  3,
+		"unset":     "${env:OP_TEST_UNSET}",
+	} {
+		if !reflect.DeepEqual(target[name], expected) {
+			t.Errorf("Mismatch [%s]. Expected [%#v] Actually [%#v]", name, expected, target[name])
+		}
+	}
+}
+
+func TestPreprocessRepositorySpecFileInclude(t *testing.T) {
+	path, err := ioutil.TempDir("", "preprocess")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+	for filename, content := range map[string]string{
+		"spec.yaml":              "uri: github.com/ops-openlight/test\ninclude:\n  - specs/*.yaml\n",
+		"specs/api.yaml":         "include:\n  - common/base.yaml\ntargets:\n  api:\n    path: api\n",
+		"specs/web.yaml":         "targets:\n  web:\n    path: web\n",
+		"specs/common/base.yaml": "vars:\n  port: \"8080\"\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(path, filename)), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(path, filename), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := PreprocessRepositorySpecFile(filepath.Join(path, "spec.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Include []string `yaml:"include"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"specs/api.yaml", "specs/common/base.yaml", "specs/web.yaml"}
+	if !reflect.DeepEqual(doc.Include, expected) {
+		t.Errorf("Mismatch include. Expected [%v] Actually [%v]", expected, doc.Include)
+	}
+}
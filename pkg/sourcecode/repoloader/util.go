@@ -7,21 +7,61 @@
 package repoloader
 
 import (
+	"errors"
+	"fmt"
 	"github.com/ops-openlight/openlight/pkg/sourcecode/spec"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
+	"os"
+	"strings"
 )
 
 func LoadRepositorySpecFromFile(filename string) (*spec.RepositorySpec, error) {
-	// Load repository spec from file
-	data, err := ioutil.ReadFile(filename)
+	// Load repository spec from file, with the includes, templates and vars resolved
+	data, err := PreprocessRepositorySpecFile(filename)
 	if err != nil {
 		return nil, err
 	}
 	var repoSpec spec.RepositorySpec
-	if err := yaml.Unmarshal(data, &repoSpec); err != nil {
+	if err := yaml.UnmarshalStrict(data, &repoSpec); err != nil {
+		return nil, err
+	}
+	// Validate the spec
+	if errs := repoSpec.Validate(); len(errs) > 0 {
+		var messages []string
+		for _, err := range errs {
+			messages = append(messages, err.Error())
+		}
+		return nil, errors.New(fmt.Sprintf("Invalid repository spec: %s", strings.Join(messages, "; ")))
+	}
+	// Done
+	return &repoSpec, nil
+}
+
+// Load repository lock from file, returns nil if the file does not exist
+func LoadRepositoryLockFromFile(filename string) (*spec.RepositoryLock, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
 		return nil, err
-	} else {
-		return &repoSpec, nil
 	}
+	var lock spec.RepositoryLock
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, err
+	}
+	if lock.Repositories == nil {
+		lock.Repositories = make(map[string]*spec.RepositoryLockEntry)
+	}
+	return &lock, nil
+}
+
+// Save repository lock to file
+func SaveRepositoryLockToFile(lock *spec.RepositoryLock, filename string) error {
+	data, err := yaml.Marshal(lock)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
 }
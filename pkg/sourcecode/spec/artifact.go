@@ -7,9 +7,18 @@
 package spec
 
 type FileArtifactCollectorSpec struct {
-	Path       string `yaml:"path"`
-	Recursive  bool   `yaml:"recursive"`
-	FollowLink bool   `yaml:"followLink"`
-	Includes   string `yaml:"includes"`
-	Excludes   string `yaml:"excludes"`
+	Path       string                    `yaml:"path"`
+	Recursive  bool                      `yaml:"recursive"`
+	FollowLink bool                      `yaml:"followLink"`
+	Includes   string                    `yaml:"includes"`
+	Excludes   string                    `yaml:"excludes"`
+	Compress   *FileArtifactCompressSpec `yaml:"compress"` // Compress the collected files into a package
+}
+
+// The compress spec of file artifact collector
+type FileArtifactCompressSpec struct {
+	Format string `yaml:"format"` // The compress format, either tar.gz, tar.zst or zip. tar.gz by default
+	Level  *int   `yaml:"level"`  // The compress level, will use the default level of the format if not specified
+	Output string `yaml:"output"` // The package file name (relative to the collector root path), will use <name>.<format> if not specified
+	Keep   bool   `yaml:"keep"`   // Keep the uncompressed artifact alongside the compressed one. The compressed artifact will be named as <name>.compressed
 }
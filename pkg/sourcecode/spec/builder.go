@@ -18,11 +18,13 @@ type SourceCodeLink struct {
 
 // The build result of a target
 type BuildResult struct {
-	Repository string                       `json:"repository"` // The repository uri
-	Target     string                       `json:"target"`     // The target name
-	Metadata   BuildMetadata                `json:"metadata"`   // The metadata
-	Artifacts  map[string]artifact.Artifact `json:"artifacts"`  // All collected artifacts
-	Deps       map[string]*BuildResult      `json:"deps"`       // The build results of dependencies, name is the dep name
+	Repository string                       `json:"repository"`        // The repository uri
+	Target     string                       `json:"target"`            // The target name
+	Metadata   BuildMetadata                `json:"metadata"`          // The metadata
+	Artifacts  map[string]artifact.Artifact `json:"artifacts"`         // All collected artifacts
+	Deps       map[string]*BuildResult      `json:"deps"`              // The build results of dependencies, name is the dep name
+	Published  map[string][]string          `json:"published"`         // The published urls, key is the artifact name
+	Summary    *BuildSummary                `json:"summary,omitempty"` // The timing summary of the build, only written in the manifest
 }
 
 type BuildMetadata struct {
@@ -37,6 +39,23 @@ type BuildMetadata struct {
 	OutputPath     string                 `json:"outputPath"`     // The build output path (root output path)
 }
 
+// The timing summary of building a target and its dependencies
+type BuildSummary struct {
+	Targets               []*TargetBuildTiming `json:"targets"`               // The timings in build order
+	CriticalPath          []string             `json:"criticalPath"`          // The target keys on the longest dependency chain, from the deepest dependency to the built target
+	CriticalPathTimeUsage float64              `json:"criticalPathTimeUsage"` // The prepare and build time of the critical path in seconds
+	TotalTimeUsage        float64              `json:"totalTimeUsage"`        // The prepare, build and copy time of the non-cached targets in seconds
+}
+
+// The timing of a target, all time usages are in seconds
+type TargetBuildTiming struct {
+	Target           string  `json:"target"` // The target key
+	PrepareTimeUsage float64 `json:"prepareTimeUsage"`
+	BuildTimeUsage   float64 `json:"buildTimeUsage"`
+	CopyTimeUsage    float64 `json:"copyTimeUsage"`
+	Cached           bool    `json:"cached"` // The build result is reused from a previous build of another target, the time usages are of the previous build
+}
+
 func NewBuildResult(target *Target, metadata BuildMetadata) *BuildResult {
 	return &BuildResult{
 		Repository: target.Repository.Uri,
@@ -44,5 +63,6 @@ func NewBuildResult(target *Target, metadata BuildMetadata) *BuildResult {
 		Metadata:   metadata,
 		Artifacts:  make(map[string]artifact.Artifact),
 		Deps:       make(map[string]*BuildResult),
+		Published:  make(map[string][]string),
 	}
 }
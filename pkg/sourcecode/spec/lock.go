@@ -0,0 +1,27 @@
+// Author: lipixun
+// Created Time : 二 12/27 14:08:51 2016
+//
+// File Name: lock.go
+// Description:
+//	The repository lock spec
+package spec
+
+// The lock of the referenced repositories, written to LockFileName beside the spec file
+type RepositoryLock struct {
+	Repositories map[string]*RepositoryLockEntry `yaml:"repositories"` // Key is repository uri
+}
+
+type RepositoryLockEntry struct {
+	Remote      string `yaml:"remote,omitempty"`      // The remote of the repository reference
+	Branch      string `yaml:"branch,omitempty"`      // The branch of the repository reference
+	Commit      string `yaml:"commit,omitempty"`      // The commit of the repository reference
+	Tag         string `yaml:"tag,omitempty"`         // The tag (or semver range) of the repository reference
+	Resolved    string `yaml:"resolved"`              // The exact commit resolved
+	Source      string `yaml:"source,omitempty"`      // How the repository is resolved when locking (root, overwrite, finder or remote)
+	ResolvedTag string `yaml:"resolvedTag,omitempty"` // The tag resolved from the tag (or semver range) reference
+}
+
+// Check if the lock entry is locked from the reference
+func (this *RepositoryLockEntry) Match(refer *RepositoryReferenceSpec) bool {
+	return this.Remote == refer.Remote && this.Branch == refer.Branch && this.Commit == refer.Commit && this.Tag == refer.Tag
+}
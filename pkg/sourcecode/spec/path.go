@@ -10,4 +10,7 @@ const (
 	OutputDir = "output"
 
 	SpecFileName = ".op.sourcecode.yaml"
+	LockFileName = ".op.sourcecode.lock"
+
+	MetadataFileName = ".op.sourcecode.metadata" // The metadata of the repository which is not a git repository
 )
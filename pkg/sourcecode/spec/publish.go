@@ -0,0 +1,30 @@
+// Author: lipixun
+// Created Time : 三 12/28 17:02:14 2016
+//
+// File Name: publish.go
+// Description:
+//	The artifact publish spec
+package spec
+
+const (
+	PublishTypeLocal = "local"
+	PublishTypeHttp  = "http"
+)
+
+type PublishSpec struct {
+	Type      string            `yaml:"type"`      // The publisher type, either local or http
+	Artifacts []string          `yaml:"artifacts"` // The name of the artifacts to publish, will publish all file artifacts if not specified
+	Local     *LocalPublishSpec `yaml:"local"`
+	Http      *HttpPublishSpec  `yaml:"http"`
+}
+
+// Publish to local filesystem
+type LocalPublishSpec struct {
+	Path string `yaml:"path"` // The root path of the store
+}
+
+// Publish to http server by PUT request
+type HttpPublishSpec struct {
+	Url     string            `yaml:"url"`     // The root url of the store
+	Headers map[string]string `yaml:"headers"` // The additional headers, e.g. Authorization
+}
@@ -6,13 +6,26 @@
 //	Python spec
 package spec
 
+const (
+	PythonBuildTypeScript = "script"
+	PythonBuildTypeNuitka = "nuitka"
+	PythonBuildTypeWheel  = "wheel"
+	PythonBuildTypeVenv   = "venv"
+
+	PythonNuitkaBuildTypeBinary = "binary"
+	PythonNuitkaBuildTypeLib    = "lib"
+)
+
 type PythonBuildSpec struct {
 	Name        string                 `yaml:"name"`        // The name of this build, the generated artifact will be use the same name
-	Type        string                 `yaml:"type"`        // The build type, either script or nuitka
+	Type        string                 `yaml:"type"`        // The build type, either script, nuitka, wheel or venv
+	Interpreter string                 `yaml:"interpreter"` // The python interpreter, "python" by default
 	Links       []SourceCodeLink       `yaml:"links"`       // The target to link into the package
 	ModulePaths []string               `yaml:"modulePaths"` // The path to the parent of the module, not the module itself. This path will be added to PYTHONPATH. Will use the target path if not specified
 	Script      *PythonSetupBuildSpec  `yaml:"script"`      // The python script build spec
 	Nuitka      *PythonNuitkaBuildSpec `yaml:"nuitka"`      // The python nuitka build spec
+	Wheel       *PythonWheelBuildSpec  `yaml:"wheel"`       // The python wheel build spec
+	Venv        *PythonVenvBuildSpec   `yaml:"venv"`        // The python virtualenv build spec
 }
 
 // The python setup script build spec
@@ -21,18 +34,31 @@ type PythonSetupBuildSpec struct {
 	Command    string `yaml:"command"` // The command of the script, "sdist" by default
 }
 
+// The spec used to build PEP 517 wheel via pip
+type PythonWheelBuildSpec struct {
+	Args []string `yaml:"args"` // The additional arguments of pip wheel
+}
+
+// The spec used to build a virtualenv with the target and its python dependency targets installed
+type PythonVenvBuildSpec struct {
+	Requirements string   `yaml:"requirements"` // The requirements file (relative to the target path), "requirements.txt" by default. Ignored if not existed
+	Args         []string `yaml:"args"`         // The additional arguments of pip install
+	Output       string   `yaml:"output"`       // The tarball file name, "<name>.tar.gz" by default
+}
+
 // The spec used to build via nuitka
 type PythonNuitkaBuildSpec struct {
 	Type    string                       `yaml:"type"`    // The build type, either binary or lib
 	Binary  *PythonNuitkaBinaryBuildSpec `yaml:"binary"`  // The binary build spec
-	Lib     *PythonNuitkaLibBuildSpac    `yaml:"lib"`     // The lib build spec
-	Modules []string                     `yaml:"modules"` // The module names to build within nuitka
-	Output  string                       `yaml:"output"`  // The output binary name
+	Lib     *PythonNuitkaLibBuildSpec    `yaml:"lib"`     // The lib build spec
+	Modules []string                     `yaml:"modules"` // The module names to build within nuitka. For lib, each module (or package) is compiled into an extension module
+	Output  string                       `yaml:"output"`  // The output binary name, only for binary
 }
 
 type PythonNuitkaBinaryBuildSpec struct {
 	EntryScript string `yaml:"entry"` // The script file as entry file, this is a must
 }
 
-type PythonNuitkaLibBuildSpac struct {
+type PythonNuitkaLibBuildSpec struct {
+	Args []string `yaml:"args"` // The additional arguments of nuitka when compiling each module
 }
@@ -8,10 +8,21 @@ package spec
 
 import (
 	"fmt"
+	"github.com/ops-openlight/openlight/pkg/uri"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 )
 
 const (
-	DefaultRepositoryType = "git"
+	RepositoryTypeGit     = "git"
+	RepositoryTypeDir     = "dir"
+	RepositoryTypeArchive = "archive"
+
+	DefaultRepositoryType = RepositoryTypeGit
+
+	ChecksumPrefixSha256 = "sha256:"
 )
 
 type Repository struct {
@@ -31,17 +42,43 @@ func (this *Repository) String() string {
 }
 
 type RepositoryLocalInfo struct {
-	Path string
+	Path       string
+	Submodules []*RepositorySubmodule // The git submodules, sorted by path
 }
 
 func (this *RepositoryLocalInfo) String() string {
 	return this.Path
 }
 
+// Get the submodule which contains the path (absolute), returns nil if the path is not in any submodule
+func (this *RepositoryLocalInfo) GetSubmodule(p string) *RepositorySubmodule {
+	for _, submodule := range this.Submodules {
+		if p == submodule.Path || strings.HasPrefix(p, submodule.Path+string(filepath.Separator)) {
+			return submodule
+		}
+	}
+	return nil
+}
+
+type RepositorySubmodule struct {
+	Path        string // The absolute path of the submodule
+	Initialized bool   // The submodule is checked out or not
+}
+
 type RepositoryMetadata struct {
-	Branch  string `json:"branch"`
-	Commit  string `json:"commit"`
-	Message string `json:"message"`
+	Branch        string            `json:"branch" yaml:"branch"`
+	Commit        string            `json:"commit" yaml:"commit"`
+	Message       string            `json:"message" yaml:"message"`
+	Describe      string            `json:"describe" yaml:"describe"`                               // The commit described by tags, e.g. v1.0-3-g1a2b3c4
+	Dirty         bool              `json:"dirty" yaml:"dirty"`                                     // The working tree has uncommitted changes
+	Detached      bool              `json:"detached" yaml:"detached"`                               // The HEAD is detached, the branch is got from environment variables (e.g. CI_BRANCH) if any
+	ModifiedFiles []string          `json:"modifiedFiles,omitempty" yaml:"modifiedFiles,omitempty"` // The uncommitted changed files relative to the repository root
+	Author        string            `json:"author" yaml:"author"`                                   // The author of the commit, format: name <email>
+	CommitTime    time.Time         `json:"commitTime" yaml:"commitTime"`                           // The committer time of the commit
+	Remote        string            `json:"remote" yaml:"remote"`                                   // The url of the origin remote
+	Tags          []string          `json:"tags,omitempty" yaml:"tags,omitempty"`                   // The tags pointing at the commit
+	ResolvedTag   string            `json:"resolvedTag,omitempty" yaml:"resolvedTag,omitempty"`     // The tag resolved from the tag (or semver range) reference
+	Submodules    map[string]string `json:"submodules,omitempty" yaml:"submodules,omitempty"`       // The checked out commits of the git submodules, keyed by path relative to the repository root
 }
 
 func (this *RepositoryMetadata) String() string {
@@ -58,14 +95,46 @@ type RepositorySpec struct {
 			} `yaml:"build"`
 		} `yaml:"default"`
 	} `yaml:"options"`
+	Include    []string                            `yaml:"include"`    // The other spec files to include (relative to this file, glob pattern is supported), all included files relative to the repository root after preprocessing
+	Vars       map[string]string                   `yaml:"vars"`       // The variables used by ${var} substitution in the string values (${env:NAME} for environment variables), the undeclared ${...} are kept as is
+	Templates  map[string]*TargetSpec              `yaml:"templates"`  // The target templates, key is template name
 	References map[string]*RepositoryReferenceSpec `yaml:"references"` // Key is repository uri
+	Overrides  map[string]*RepositoryOverrideSpec  `yaml:"overrides"`  // Key is repository uri, only used in root repository with override conflict policy
 	Targets    map[string]*TargetSpec              `yaml:"targets"`    // Key is target name
 }
 
-type RepositoryReferenceSpec struct {
-	Remote string `yaml:"remote"` // The repository remote path, either a local path or url
+// Get the names (sorted) of the targets matched by the target uri
+func (this *RepositorySpec) GetTargetNamesByUri(targetUri *uri.TargetUri) []string {
+	var names []string
+	for name, targetSpec := range this.Targets {
+		if targetUri.Match(name, targetSpec.Path) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	// Done
+	return names
+}
+
+// Override the ref of the repository requested by any repository in the graph
+type RepositoryOverrideSpec struct {
 	Branch string `yaml:"branch"`
 	Commit string `yaml:"commit"`
+	Tag    string `yaml:"tag"`
+}
+
+type RepositoryReferenceSpec struct {
+	Type     string `yaml:"type"`   // The repository type (git, dir or archive), empty means git
+	Remote   string `yaml:"remote"` // The repository remote path, either a local path or url
+	Branch   string `yaml:"branch"`
+	Commit   string `yaml:"commit"`
+	Tag      string `yaml:"tag"`      // The tag or semver range (resolved against the tags of the remote), e.g. ^1.2
+	Subdir   string `yaml:"subdir"`   // The directory of the spec file relative to the repository root
+	Checksum string `yaml:"checksum"` // The checksum of the archive, format: sha256:<hex>
+	Options  struct {
+		Submodules bool `yaml:"submodules"` // Init and update the git submodules recursively
+		LFS        bool `yaml:"lfs"`        // Fetch the git LFS objects (from the local LFS store if configured)
+	} `yaml:"options"` // The git repository options
 	Finder struct {
 		Type   string                 `yaml:"type"`
 		Params map[string]interface{} `yaml:"params"`
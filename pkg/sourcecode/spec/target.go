@@ -11,6 +11,13 @@ import (
 	"path/filepath"
 )
 
+const (
+	BuildTypeShell  = "shell"
+	BuildTypeDocker = "docker"
+	BuildTypeGolang = "golang"
+	BuildTypePython = "python"
+)
+
 type Target struct {
 	Name       string
 	Repository *Repository
@@ -32,15 +39,17 @@ func GetTargetKey(target string, r *Repository) string {
 }
 
 type TargetSpec struct {
-	Path  string `yaml:"path"` // The relative path of the target in the repository
-	Build struct {
+	Template string `yaml:"template"` // The template name, the fields defined in this target override the template
+	Path     string `yaml:"path"`     // The relative path of the target in the repository
+	Build    struct {
 		Type   string           `yaml:"type"` // The build type of the target
 		Shell  *ShellBuildSpec  `yaml:"shell"`
 		Docker *DockerBuildSpec `yaml:"docker"`
 		Golang *GolangBuildSpec `yaml:"golang"`
 		Python *PythonBuildSpec `yaml:"python"`
 	} `yaml:"build"`
-	Deps map[string]*TargetDependencySpec `yaml:"deps"` // The key is target dependency name
+	Deps    map[string]*TargetDependencySpec `yaml:"deps"`    // The key is target dependency name
+	Publish []*PublishSpec                   `yaml:"publish"` // The artifact stores to publish the artifacts after built
 }
 
 type TargetDependencySpec struct {
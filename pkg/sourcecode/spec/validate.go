@@ -0,0 +1,303 @@
+// Author: lipixun
+// Created Time : 三 12/28 10:12:47 2016
+//
+// File Name: validate.go
+// Description:
+//	Validate the repository spec
+package spec
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ops-openlight/openlight/pkg/artifact"
+	"github.com/ops-openlight/openlight/pkg/uri"
+	"sort"
+	"strings"
+)
+
+// The error found in repository spec
+type SpecError struct {
+	Path    []string // The path of the field in spec, e.g. [targets api build docker image]
+	Line    int      // The line in spec file, 0 means unknown
+	Column  int      // The column in spec file, 0 means unknown
+	Message string
+}
+
+func (this *SpecError) Error() string {
+	if len(this.Path) == 0 {
+		return this.Message
+	}
+	return fmt.Sprintf("%s: %s", strings.Join(this.Path, "."), this.Message)
+}
+
+// Validate the repository spec
+// Returns:
+// 	All errors found, empty means the spec is valid
+func (this *RepositorySpec) Validate() []*SpecError {
+	var errs []*SpecError
+	addError := func(message string, path ...string) {
+		errs = append(errs, &SpecError{Path: path, Message: message})
+	}
+	if this.Uri == "" {
+		addError("Require uri", "uri")
+	}
+	if this.Options.Default.Build.Target != "" {
+		if _, ok := this.Targets[this.Options.Default.Build.Target]; !ok {
+			addError(fmt.Sprintf("Target [%s] not found", this.Options.Default.Build.Target), "options", "default", "build", "target")
+		}
+	}
+	for uri, refer := range this.References {
+		if refer == nil {
+			continue
+		}
+		if err := refer.validateRef(); err != nil {
+			addError(err.Error(), "references", uri)
+		}
+		if refer.Remote == "" && refer.Finder.Type == "" {
+			addError("Require either remote or finder", "references", uri)
+		}
+	}
+	for uri := range this.Overrides {
+		if _, ok := this.References[uri]; !ok {
+			addError(fmt.Sprintf("Overridden repository [%s] is not referenced", uri), "overrides", uri)
+		}
+	}
+	// Validate the targets (sorted to keep the errors in stable order)
+	var names []string
+	for name := range this.Targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		targetSpec := this.Targets[name]
+		if targetSpec == nil {
+			addError("Empty target", "targets", name)
+			continue
+		}
+		for _, err := range this.validateTarget(targetSpec) {
+			err.Path = append([]string{"targets", name}, err.Path...)
+			errs = append(errs, err)
+		}
+	}
+	// Done
+	return errs
+}
+
+// Validate the ref (branch, commit or tag) and the type of the repository reference
+func (this *RepositoryReferenceSpec) validateRef() error {
+	refs := 0
+	for _, ref := range []string{this.Branch, this.Commit, this.Tag} {
+		if ref != "" {
+			refs++
+		}
+	}
+	if refs > 1 {
+		return errors.New("Cannot specify more than one of branch, commit and tag")
+	}
+	if uri.IsVersionRange(this.Tag) {
+		if _, err := uri.ParseVersionRange(this.Tag); err != nil {
+			return err
+		}
+	}
+	switch this.Type {
+	case "", RepositoryTypeGit:
+		if this.Checksum != "" {
+			return errors.New("Checksum is only supported by archive repository")
+		}
+	case RepositoryTypeDir, RepositoryTypeArchive:
+		if refs > 0 {
+			return errors.New(fmt.Sprintf("Branch, commit and tag are not supported by %s repository", this.Type))
+		}
+		if this.Options.Submodules || this.Options.LFS {
+			return errors.New(fmt.Sprintf("Submodules and LFS are not supported by %s repository", this.Type))
+		}
+		if this.Checksum != "" && this.Type != RepositoryTypeArchive {
+			return errors.New("Checksum is only supported by archive repository")
+		}
+		if this.Checksum != "" && !strings.HasPrefix(this.Checksum, ChecksumPrefixSha256) {
+			return errors.New(fmt.Sprintf("Invalid checksum [%s], format: %s<hex>", this.Checksum, ChecksumPrefixSha256))
+		}
+		if this.Type == RepositoryTypeArchive && this.Checksum == "" && (strings.HasPrefix(this.Remote, "http://") || strings.HasPrefix(this.Remote, "https://")) {
+			return errors.New("Checksum is required by the archive repository downloaded from http(s) remote")
+		}
+	default:
+		return errors.New(fmt.Sprintf("Unknown repository type [%s]", this.Type))
+	}
+	// Done
+	return nil
+}
+
+func (this *RepositorySpec) validateTarget(targetSpec *TargetSpec) []*SpecError {
+	var errs []*SpecError
+	addError := func(message string, path ...string) {
+		errs = append(errs, &SpecError{Path: path, Message: message})
+	}
+	// Check the build type and the sections
+	sections := map[string]bool{
+		BuildTypeShell:  targetSpec.Build.Shell != nil,
+		BuildTypeDocker: targetSpec.Build.Docker != nil,
+		BuildTypeGolang: targetSpec.Build.Golang != nil,
+		BuildTypePython: targetSpec.Build.Python != nil,
+	}
+	t := targetSpec.Build.Type
+	if t == "" {
+		addError("Require build type", "build", "type")
+	} else if _, ok := sections[t]; !ok {
+		addError(fmt.Sprintf("Unknown build type [%s]", t), "build", "type")
+	} else if !sections[t] {
+		addError(fmt.Sprintf("Build section [%s] is required by build type [%s]", t, t), "build")
+	}
+	for _, section := range []string{BuildTypeShell, BuildTypeDocker, BuildTypeGolang, BuildTypePython} {
+		if sections[section] && section != t {
+			addError(fmt.Sprintf("Build section [%s] is defined but build type is [%s]", section, t), "build", section)
+		}
+	}
+	// Check the required fields of the builder
+	switch t {
+	case BuildTypeShell:
+		if targetSpec.Build.Shell != nil {
+			errs = append(errs, validateShellBuildSpec(targetSpec.Build.Shell)...)
+		}
+	case BuildTypeDocker:
+		if targetSpec.Build.Docker != nil {
+			errs = append(errs, validateDockerBuildSpec(targetSpec.Build.Docker, targetSpec)...)
+		}
+	case BuildTypeGolang:
+		if targetSpec.Build.Golang != nil && targetSpec.Build.Golang.Package == "" && len(targetSpec.Build.Golang.BuildPackages) == 0 {
+			addError("Require package or buildPackages", "build", "golang", "package")
+		}
+	case BuildTypePython:
+		if targetSpec.Build.Python != nil {
+			errs = append(errs, validatePythonBuildSpec(targetSpec.Build.Python)...)
+		}
+	}
+	// Check the dependencies
+	for name, depSpec := range targetSpec.Deps {
+		if depSpec == nil {
+			addError("Empty dependency", "deps", name)
+			continue
+		}
+		if depSpec.Target == "" {
+			addError("Require target", "deps", name, "target")
+		} else if depSpec.Repository == "" || depSpec.Repository == this.Uri {
+			if _, ok := this.Targets[depSpec.Target]; !ok {
+				addError(fmt.Sprintf("Target [%s] not found", depSpec.Target), "deps", name, "target")
+			}
+		} else if _, ok := this.References[depSpec.Repository]; !ok {
+			addError(fmt.Sprintf("Repository [%s] is not defined in references", depSpec.Repository), "deps", name, "repository")
+		}
+	}
+	// Check the publish
+	for i, publishSpec := range targetSpec.Publish {
+		index := fmt.Sprintf("%d", i)
+		if publishSpec == nil {
+			addError("Empty publish", "publish", index)
+			continue
+		}
+		switch publishSpec.Type {
+		case PublishTypeLocal:
+			if publishSpec.Local == nil || publishSpec.Local.Path == "" {
+				addError("Require local path", "publish", index, "local")
+			}
+		case PublishTypeHttp:
+			if publishSpec.Http == nil || publishSpec.Http.Url == "" {
+				addError("Require http url", "publish", index, "http")
+			}
+		default:
+			addError(fmt.Sprintf("Unknown publisher type [%s]", publishSpec.Type), "publish", index, "type")
+		}
+	}
+	// Done
+	return errs
+}
+
+func validateShellBuildSpec(shellSpec *ShellBuildSpec) []*SpecError {
+	var errs []*SpecError
+	if shellSpec.Command == "" {
+		errs = append(errs, &SpecError{Path: []string{"build", "shell", "command"}, Message: "Require command"})
+	}
+	if len(shellSpec.Collectors) == 0 {
+		errs = append(errs, &SpecError{Path: []string{"build", "shell", "collectors"}, Message: "Require at least one collector"})
+	}
+	for name, collector := range shellSpec.Collectors {
+		if collector == nil || collector.Compress == nil || collector.Compress.Format == "" {
+			continue
+		}
+		valid := false
+		for _, format := range artifact.GetCompressFormats() {
+			if collector.Compress.Format == format {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			errs = append(errs, &SpecError{
+				Path:    []string{"build", "shell", "collectors", name, "compress", "format"},
+				Message: fmt.Sprintf("Unknown compress format [%s]", collector.Compress.Format),
+			})
+		}
+	}
+	return errs
+}
+
+func validateDockerBuildSpec(dockerSpec *DockerBuildSpec, targetSpec *TargetSpec) []*SpecError {
+	var errs []*SpecError
+	addError := func(message string, path ...string) {
+		errs = append(errs, &SpecError{Path: append([]string{"build", "docker"}, path...), Message: message})
+	}
+	if dockerSpec.Image == "" {
+		addError("Require image", "image")
+	}
+	for i, f := range dockerSpec.Files {
+		index := fmt.Sprintf("%d", i)
+		if f.Source.Local != nil && f.Source.Dep != nil {
+			addError("Cannot define local and dep at the same time", "files", index, "source")
+		} else if f.Source.Local == nil && f.Source.Dep == nil {
+			addError("Require either local or dep", "files", index, "source")
+		} else if f.Source.Dep != nil {
+			if _, ok := targetSpec.Deps[f.Source.Dep.Name]; !ok {
+				addError(fmt.Sprintf("Dependency [%s] is not declared in deps", f.Source.Dep.Name), "files", index, "source", "dep", "name")
+			}
+			if f.Source.Dep.Artifact == "" {
+				addError("Require artifact", "files", index, "source", "dep", "artifact")
+			}
+		}
+	}
+	return errs
+}
+
+func validatePythonBuildSpec(pythonSpec *PythonBuildSpec) []*SpecError {
+	var errs []*SpecError
+	addError := func(message string, path ...string) {
+		errs = append(errs, &SpecError{Path: append([]string{"build", "python"}, path...), Message: message})
+	}
+	switch pythonSpec.Type {
+	case "":
+		// The target is only prepared (linked into the environment) if no type specified
+	case PythonBuildTypeScript, PythonBuildTypeWheel, PythonBuildTypeVenv:
+	case PythonBuildTypeNuitka:
+		nuitkaSpec := pythonSpec.Nuitka
+		if nuitkaSpec == nil {
+			addError("Require nuitka section", "nuitka")
+			break
+		}
+		switch nuitkaSpec.Type {
+		case PythonNuitkaBuildTypeBinary:
+			if nuitkaSpec.Binary == nil || nuitkaSpec.Binary.EntryScript == "" {
+				addError("Require entry script", "nuitka", "binary", "entry")
+			}
+			if nuitkaSpec.Output == "" {
+				addError("Require output", "nuitka", "output")
+			}
+		case PythonNuitkaBuildTypeLib:
+			if len(nuitkaSpec.Modules) == 0 {
+				addError("Require modules", "nuitka", "modules")
+			}
+		default:
+			addError(fmt.Sprintf("Unknown nuitka build type [%s]", nuitkaSpec.Type), "nuitka", "type")
+		}
+	default:
+		addError(fmt.Sprintf("Unknown python build type [%s]", pythonSpec.Type), "type")
+	}
+	return errs
+}
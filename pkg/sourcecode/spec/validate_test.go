@@ -0,0 +1,84 @@
+// Author: lipixun
+// Created Time : 四 12/29 22:48:13 2016
+//
+// File Name: validate_test.go
+// Description:
+//
+package spec
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"reflect"
+	"testing"
+)
+
+// The spec with a valid target lib, the target x is defined by the test case
+const testValidateSpec = `uri: github.com/ops-openlight/test
+references:
+  github.com/ops-openlight/other:
+    remote: https://github.com/ops-openlight/other.git
+targets:
+  lib:
+    build:
+      type: golang
+      golang:
+        package: github.com/ops-openlight/test/lib
+  x: %s
+`
+
+func TestValidate(t *testing.T) {
+	for _, c := range []struct {
+		Target   string
+		Expected []string
+	}{
+		{"{build: {type: golang, golang: {package: p}}}", nil},
+		// Build type vs section
+		{"{build: {}}", []string{"targets.x.build.type: Require build type"}},
+		{"{build: {type: unknown}}", []string{"targets.x.build.type: Unknown build type [unknown]"}},
+		{"{build: {type: golang, docker: {image: a}}}", []string{
+			"targets.x.build: Build section [golang] is required by build type [golang]",
+			"targets.x.build.docker: Build section [docker] is defined but build type is [golang]",
+		}},
+		{"{build: {type: golang, golang: {}}}", []string{"targets.x.build.golang.package: Require package or buildPackages"}},
+		{"{build: {type: python, python: {}}}", nil},
+		{"{build: {type: python, python: {type: nuitka}}}", []string{"targets.x.build.python.nuitka: Require nuitka section"}},
+		// Docker files
+		{"{build: {type: docker, docker: {}}}", []string{"targets.x.build.docker.image: Require image"}},
+		{"{deps: {lib: {target: lib}}, build: {type: docker, docker: {image: a, files: [{target: bin, source: {dep: {name: lib, artifact: bin}}}]}}}", nil},
+		{"{build: {type: docker, docker: {image: a, files: [{target: bin, source: {dep: {name: lib}}}]}}}", []string{
+			"targets.x.build.docker.files.0.source.dep.name: Dependency [lib] is not declared in deps",
+			"targets.x.build.docker.files.0.source.dep.artifact: Require artifact",
+		}},
+		{"{build: {type: docker, docker: {image: a, files: [{target: a, source: {local: {path: a}, dep: {name: lib, artifact: bin}}}, {target: b, source: {}}]}}}", []string{
+			"targets.x.build.docker.files.0.source: Cannot define local and dep at the same time",
+			"targets.x.build.docker.files.1.source: Require either local or dep",
+		}},
+		// Dependencies
+		{"{deps: {d: {target: lib}}, build: {type: golang, golang: {package: p}}}", nil},
+		{"{deps: {d: {target: unknown}}, build: {type: golang, golang: {package: p}}}", []string{"targets.x.deps.d.target: Target [unknown] not found"}},
+		{"{deps: {d: {target: y, repository: github.com/ops-openlight/other}}, build: {type: golang, golang: {package: p}}}", nil},
+		{"{deps: {d: {target: y, repository: github.com/ops-openlight/unknown}}, build: {type: golang, golang: {package: p}}}", []string{
+			"targets.x.deps.d.repository: Repository [github.com/ops-openlight/unknown] is not defined in references",
+		}},
+		// Publish
+		{"{build: {type: golang, golang: {package: p}}, publish: [{type: local, local: {path: /store}}, {type: http, http: {url: 'http://store'}}]}", nil},
+		{"{build: {type: golang, golang: {package: p}}, publish: [{type: local}, {type: http}, {type: ftp}]}", []string{
+			"targets.x.publish.0.local: Require local path",
+			"targets.x.publish.1.http: Require http url",
+			"targets.x.publish.2.type: Unknown publisher type [ftp]",
+		}},
+	} {
+		var repoSpec RepositorySpec
+		if err := yaml.UnmarshalStrict([]byte(fmt.Sprintf(testValidateSpec, c.Target)), &repoSpec); err != nil {
+			t.Fatalf("Failed to decode target [%s], error: %s", c.Target, err)
+		}
+		var actual []string
+		for _, err := range repoSpec.Validate() {
+			actual = append(actual, err.Error())
+		}
+		if !reflect.DeepEqual(actual, c.Expected) {
+			t.Errorf("Mismatch errors of target [%s]. Expected %q Actually %q", c.Target, c.Expected, actual)
+		}
+	}
+}
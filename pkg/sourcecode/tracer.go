@@ -4,27 +4,53 @@
 // File Name: trace.go
 // Description:
 //	The trace functions
+//	The tracer tracks the current loading (or building) path, and records the spans into the recorder if any
+//	The recorded spans could be written as a json timeline or a chrome trace-event file (open by chrome://tracing)
 package sourcecode
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"strings"
+	"sync"
+	"time"
 )
 
 const (
 	TraceTypeRepository = "Repository"
 	TraceTypeTarget     = "Target"
 	TraceTypeDependency = "Dependency"
+	TraceTypePrepare    = "Prepare"
+	TraceTypeBuild      = "Build"
+	TraceTypePublish    = "Publish"
+	TraceTypeCopy       = "Copy"
+
+	TraceOutcomeOK     = "ok"
+	TraceOutcomeFailed = "failed"
+	TraceOutcomeCached = "cached" // Loaded or built before
+
+	TraceFormatTimeline = "timeline"
+	TraceFormatChrome   = "chrome"
+	DefaultTraceFormat  = TraceFormatChrome
 )
 
 type Tracer struct {
-	path  []TraceItem
-	types map[string]map[string]int
+	path     []TraceItem
+	types    map[string]map[string]int
+	recorder *TraceRecorder
 }
 
 func NewTracer() *Tracer {
+	return NewTracerWithRecorder(nil)
+}
+
+// Create a new Tracer which records the pushed items as spans into the recorder, nil means no recording
+func NewTracerWithRecorder(recorder *TraceRecorder) *Tracer {
 	return &Tracer{
-		types: make(map[string]map[string]int),
+		types:    make(map[string]map[string]int),
+		recorder: recorder,
 	}
 }
 
@@ -32,6 +58,7 @@ type TraceItem struct {
 	Type string
 	Key  string
 	Name string
+	span *TraceSpan
 }
 
 func (this *Tracer) Has(t, key string) bool {
@@ -43,7 +70,7 @@ func (this *Tracer) Has(t, key string) bool {
 }
 
 func (this *Tracer) Push(t, key, name string) {
-	this.path = append(this.path, TraceItem{Type: t, Key: key, Name: name})
+	this.path = append(this.path, TraceItem{Type: t, Key: key, Name: name, span: this.recorder.Start(t, key, name)})
 	keys, ok := this.types[t]
 	if !ok {
 		this.types[t] = map[string]int{key: 1}
@@ -53,9 +80,15 @@ func (this *Tracer) Push(t, key, name string) {
 }
 
 func (this *Tracer) Pop() {
+	this.PopWithError(nil)
+}
+
+// Pop the current item, the span of the item is finished as failed if err is not nil
+func (this *Tracer) PopWithError(err error) {
 	if len(this.path) > 0 {
 		item := this.path[len(this.path)-1]
 		this.path = this.path[:len(this.path)-1]
+		item.span.Finish(err)
 		keys, ok := this.types[item.Type]
 		if ok {
 			value, ok := keys[item.Key]
@@ -70,6 +103,13 @@ func (this *Tracer) Pop() {
 	}
 }
 
+// Set the outcome of the span of the current item
+func (this *Tracer) SetOutcome(outcome string) {
+	if len(this.path) > 0 {
+		this.path[len(this.path)-1].span.SetOutcome(outcome)
+	}
+}
+
 func (this *Tracer) String() string {
 	var strs []string
 	for _, item := range this.path {
@@ -77,3 +117,149 @@ func (this *Tracer) String() string {
 	}
 	return strings.Join(strs, " --> ")
 }
+
+// The recorded span
+type TraceSpan struct {
+	Type    string    `json:"type"`
+	Key     string    `json:"key"`
+	Name    string    `json:"name"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Outcome string    `json:"outcome"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// Set the outcome, the span which is finished without outcome is ok (or failed if ended with error)
+func (this *TraceSpan) SetOutcome(outcome string) {
+	if this != nil {
+		this.Outcome = outcome
+	}
+}
+
+// Finish the span
+func (this *TraceSpan) Finish(err error) {
+	if this == nil {
+		return
+	}
+	this.End = time.Now()
+	if err != nil {
+		this.Outcome = TraceOutcomeFailed
+		this.Error = err.Error()
+	} else if this.Outcome == "" {
+		this.Outcome = TraceOutcomeOK
+	}
+}
+
+// Get the duration of the span, zero if not finished
+func (this *TraceSpan) Duration() time.Duration {
+	if this.End.IsZero() {
+		return 0
+	}
+	return this.End.Sub(this.Start)
+}
+
+// The recorder of spans, all methods could be called on nil recorder (which records nothing)
+type TraceRecorder struct {
+	lock  sync.Mutex
+	start time.Time
+	spans []*TraceSpan
+}
+
+func NewTraceRecorder() *TraceRecorder {
+	return &TraceRecorder{start: time.Now()}
+}
+
+// Start a new span, returns nil if the recorder is nil
+func (this *TraceRecorder) Start(t, key, name string) *TraceSpan {
+	if this == nil {
+		return nil
+	}
+	span := &TraceSpan{Type: t, Key: key, Name: name, Start: time.Now()}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.spans = append(this.spans, span)
+	return span
+}
+
+// Get the recorded spans ordered by start time
+func (this *TraceRecorder) Spans() []*TraceSpan {
+	if this == nil {
+		return nil
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return append([]*TraceSpan(nil), this.spans...)
+}
+
+// The json timeline
+type traceTimeline struct {
+	Start time.Time    `json:"start"`
+	Spans []*TraceSpan `json:"spans"`
+}
+
+// The chrome trace-event file, each span is written as a complete (X) event
+type chromeTrace struct {
+	TraceEvents     []chromeTraceEvent `json:"traceEvents"`
+	DisplayTimeUnit string             `json:"displayTimeUnit"`
+}
+
+type chromeTraceEvent struct {
+	Name      string            `json:"name"`
+	Category  string            `json:"cat"`
+	Phase     string            `json:"ph"`
+	Timestamp int64             `json:"ts"`  // In microseconds
+	Duration  int64             `json:"dur"` // In microseconds
+	Pid       int               `json:"pid"`
+	Tid       int               `json:"tid"`
+	Args      map[string]string `json:"args"`
+}
+
+// Write the recorded spans to file
+// Parameters:
+// 	filename 	The file to write
+// 	format 		The format, timeline or chrome, empty means DefaultTraceFormat
+func (this *TraceRecorder) WriteFile(filename, format string) error {
+	if this == nil {
+		return errors.New("Require recorder")
+	}
+	if format == "" {
+		format = DefaultTraceFormat
+	}
+	spans := this.Spans()
+	var v interface{}
+	switch format {
+	case TraceFormatTimeline:
+		v = traceTimeline{Start: this.start, Spans: spans}
+	case TraceFormatChrome:
+		trace := chromeTrace{TraceEvents: []chromeTraceEvent{}, DisplayTimeUnit: "ms"}
+		for _, span := range spans {
+			// The span which is not finished (e.g. interrupted by error) is ended at now
+			end := span.End
+			if end.IsZero() {
+				end = time.Now()
+			}
+			args := map[string]string{"key": span.Key, "outcome": span.Outcome}
+			if span.Error != "" {
+				args["error"] = span.Error
+			}
+			trace.TraceEvents = append(trace.TraceEvents, chromeTraceEvent{
+				Name:      fmt.Sprintf("%s:%s", span.Type, span.Name),
+				Category:  span.Type,
+				Phase:     "X",
+				Timestamp: int64(span.Start.Sub(this.start) / time.Microsecond),
+				Duration:  int64(end.Sub(span.Start) / time.Microsecond),
+				Pid:       1,
+				Tid:       1,
+				Args:      args,
+			})
+		}
+		v = trace
+	default:
+		return errors.New(fmt.Sprintf("Unknown trace format [%s]", format))
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
@@ -4,28 +4,44 @@
 // File Name: uri.go
 // Description:
 // 	The repository uri format:
-// 		<uri>(///(@<branch>)|(=<commit>))?
+// 		<uri>(///((@<branch>)|(=<commit>)|(#<tag>))?(:<subdir>)?)?
+//	The tag could be a semver range which is resolved against the tags of the repository, e.g. #^1.2 or #>=1.0,<2.0
+//	The subdir selects the repository whose spec file lives below the git root
 //	The target uri, format:
-// 		(<uri>(///(@<branch>)|(=<commit>))?::)?<target>
+// 		(<repository uri>::)?<target>
+//	The target could also be a pattern:
+//		<glob>			Match target names, e.g. svc-*
+//		//<path>/...	Match targets under the path
 package uri
 
 import (
 	"errors"
 	"fmt"
+	"path"
 	"regexp"
+	"strings"
 )
 
 const (
 	UriRegex    = "(?P<uri>(([^/|:]*((/|//|:)[^/:]+)?)+))"
-	BranchRegex = "(@(?P<branch>[a-zA-Z0-9-_]+))"
+	BranchRegex = "(@(?P<branch>[a-zA-Z0-9_-][a-zA-Z0-9_.-]*(/[a-zA-Z0-9_-][a-zA-Z0-9_.-]*)*))"
 	CommitRegex = "(=(?P<commit>[a-zA-Z0-9-_]+))"
-	TargetRegex = "(?P<target>[a-zA-Z0-9_-]+)"
+	TagRegex    = "(#(?P<tag>[a-zA-Z0-9_.+^~<>=*,-]+(/[a-zA-Z0-9_.+-]+)*))"
+	SubdirRegex = "(?P<subdir>[a-zA-Z0-9_-][a-zA-Z0-9_.-]*(/[a-zA-Z0-9_-][a-zA-Z0-9_.-]*)*)"
+	TargetRegex = "((?P<target>[a-zA-Z0-9_*?-]+)|(//(?P<path>\\.\\.\\.|[a-zA-Z0-9_-][a-zA-Z0-9_.-]*(/[a-zA-Z0-9_-][a-zA-Z0-9_.-]*)*(/\\.\\.\\.)?)))"
+)
+
+const (
+	TargetPathRecursiveSuffix = "..."
 )
 
 var (
-	RepositoryUriRegex      = regexp.MustCompile(fmt.Sprintf("^%s(///(%s|%s))?$", UriRegex, BranchRegex, CommitRegex))
+	// The selector of repository: ///(ref)?(:subdir)?
+	repositorySelectorRegex = fmt.Sprintf("(///(((%s|%s|%s)(:%s)?)|(:%s)))", BranchRegex, CommitRegex, TagRegex, SubdirRegex, SubdirRegex)
+
+	RepositoryUriRegex      = regexp.MustCompile(fmt.Sprintf("^%s%s?$", UriRegex, repositorySelectorRegex))
 	RepositoryUriRegexNames = RepositoryUriRegex.SubexpNames()
-	TargetUriRegex          = regexp.MustCompile(fmt.Sprintf("^(%s|(%s(///(%s|%s))?::%s)|(%s(///(%s|%s))?))$", TargetRegex, UriRegex, BranchRegex, CommitRegex, TargetRegex, UriRegex, BranchRegex, CommitRegex))
+	TargetUriRegex          = regexp.MustCompile(fmt.Sprintf("^(%s|(%s%s?::%s)|(%s%s?))$", TargetRegex, UriRegex, repositorySelectorRegex, TargetRegex, UriRegex, repositorySelectorRegex))
 	TargetUriRegexNames     = TargetUriRegex.SubexpNames()
 )
 
@@ -33,6 +49,8 @@ type RepositoryUri struct {
 	Uri    string `json:"uri" yaml:"uri"`
 	Branch string `json:"branch" yaml:"branch"`
 	Commit string `json:"commit" yaml:"commit"`
+	Tag    string `json:"tag,omitempty" yaml:"tag,omitempty"`       // The tag or semver range of tags
+	Subdir string `json:"subdir,omitempty" yaml:"subdir,omitempty"` // The sub directory of the spec file relative to the git root
 }
 
 func ParseRepositoryUri(u string) *RepositoryUri {
@@ -51,6 +69,10 @@ func ParseRepositoryUri(u string) *RepositoryUri {
 				uri.Branch = match
 			case "commit":
 				uri.Commit = match
+			case "tag":
+				uri.Tag = match
+			case "subdir":
+				uri.Subdir = match
 			}
 		}
 	}
@@ -62,32 +84,55 @@ func (this *RepositoryUri) Validate() error {
 	if this.Uri == "" {
 		return errors.New("Require uri")
 	}
-	if this.Branch != "" && this.Commit != "" {
-		return errors.New("Cannot specify both branch and commit")
+	refs := 0
+	for _, ref := range []string{this.Branch, this.Commit, this.Tag} {
+		if ref != "" {
+			refs++
+		}
+	}
+	if refs > 1 {
+		return errors.New("Cannot specify more than one of branch, commit and tag")
+	}
+	if this.IsTagRange() {
+		if _, err := ParseVersionRange(this.Tag); err != nil {
+			return err
+		}
 	}
 	// Done
 	return nil
 }
 
+// Check if the tag is a semver range rather than an exact tag
+func (this *RepositoryUri) IsTagRange() bool {
+	return IsVersionRange(this.Tag)
+}
+
 func (this *RepositoryUri) Equal(uri *RepositoryUri) bool {
-	return this.Uri == uri.Uri && this.Branch == uri.Branch && this.Commit == uri.Commit
+	return this.Uri == uri.Uri && this.Branch == uri.Branch && this.Commit == uri.Commit && this.Tag == uri.Tag && this.Subdir == uri.Subdir
 }
 
 func (this *RepositoryUri) String() string {
-	if this.Branch == "" && this.Commit == "" {
-		return this.Uri
-	} else if this.Branch != "" {
-		return fmt.Sprintf("%s///@%s", this.Uri, this.Branch)
+	var ref string
+	if this.Branch != "" {
+		ref = "@" + this.Branch
 	} else if this.Commit != "" {
-		return fmt.Sprintf("%s///=%s", this.Uri, this.Commit)
+		ref = "=" + this.Commit
+	} else if this.Tag != "" {
+		ref = "#" + this.Tag
+	}
+	if this.Subdir != "" {
+		return fmt.Sprintf("%s///%s:%s", this.Uri, ref, this.Subdir)
+	} else if ref != "" {
+		return fmt.Sprintf("%s///%s", this.Uri, ref)
 	} else {
-		return fmt.Sprintf("<!RepositoryUri Invalid>(Uri=%s,Branch=%s,Commit=%s)", this.Uri, this.Branch, this.Commit)
+		return this.Uri
 	}
 }
 
 type TargetUri struct {
 	Repository *RepositoryUri `json:"repository" yaml:"repository"`
 	Name       string         `json:"name" yaml:"name"`
+	Path       string         `json:"path,omitempty" yaml:"path,omitempty"`
 }
 
 func ParseTargetUri(u string) *TargetUri {
@@ -116,8 +161,20 @@ func ParseTargetUri(u string) *TargetUri {
 					uri.Repository = new(RepositoryUri)
 				}
 				uri.Repository.Commit = match
+			case "tag":
+				if uri.Repository == nil {
+					uri.Repository = new(RepositoryUri)
+				}
+				uri.Repository.Tag = match
+			case "subdir":
+				if uri.Repository == nil {
+					uri.Repository = new(RepositoryUri)
+				}
+				uri.Repository.Subdir = match
 			case "target":
 				uri.Name = match
+			case "path":
+				uri.Path = match
 			}
 		}
 	}
@@ -126,7 +183,7 @@ func ParseTargetUri(u string) *TargetUri {
 }
 
 func (this *TargetUri) Equal(uri *TargetUri) bool {
-	if this.Name != uri.Name {
+	if this.Name != uri.Name || this.Path != uri.Path {
 		return false
 	}
 	if (this.Repository == nil && uri.Repository != nil) || (this.Repository != nil && uri.Repository == nil) {
@@ -139,18 +196,48 @@ func (this *TargetUri) Equal(uri *TargetUri) bool {
 	}
 }
 
+// Get the target part (the name or the path pattern) of the target uri
+func (this *TargetUri) Target() string {
+	if this.Path != "" {
+		return "//" + this.Path
+	}
+	return this.Name
+}
+
 func (this *TargetUri) String() string {
+	target := this.Target()
 	if this.Repository != nil {
-		if this.Name != "" {
-			return fmt.Sprintf("%s::%s", this.Repository.String(), this.Name)
+		if target != "" {
+			return fmt.Sprintf("%s::%s", this.Repository.String(), target)
 		} else {
 			return this.Repository.String()
 		}
 	} else {
-		if this.Name != "" {
-			return this.Name
-		} else {
-			return ""
+		return target
+	}
+}
+
+// Check if the target uri is a pattern which could match multiple targets
+func (this *TargetUri) IsPattern() bool {
+	return this.Path != "" || strings.ContainsAny(this.Name, "*?")
+}
+
+// Check if a target matches the target uri
+// Parameters:
+// 	name 		The target name
+// 	targetPath 	The target path (relative to the repository root)
+func (this *TargetUri) Match(name, targetPath string) bool {
+	if this.Path != "" {
+		targetPath = path.Clean(strings.Trim(targetPath, "/"))
+		if this.Path == TargetPathRecursiveSuffix {
+			return true
+		}
+		if strings.HasSuffix(this.Path, "/"+TargetPathRecursiveSuffix) {
+			prefix := strings.TrimSuffix(this.Path, "/"+TargetPathRecursiveSuffix)
+			return targetPath == prefix || strings.HasPrefix(targetPath, prefix+"/")
 		}
+		return targetPath == this.Path
 	}
+	matched, err := path.Match(this.Name, name)
+	return err == nil && matched
 }
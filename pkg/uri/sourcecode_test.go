@@ -43,6 +43,66 @@ var (
 			Good:      true,
 			Uri:       RepositoryUri{Uri: "repouri", Commit: "commit"},
 		},
+		{
+			Source:    "github.com/org/repo///@release/1.2",
+			Stringify: "github.com/org/repo///@release/1.2",
+			Good:      true,
+			Uri:       RepositoryUri{Uri: "github.com/org/repo", Branch: "release/1.2"},
+		},
+		{
+			Source:    "repouri///@feature.x",
+			Stringify: "repouri///@feature.x",
+			Good:      true,
+			Uri:       RepositoryUri{Uri: "repouri", Branch: "feature.x"},
+		},
+		{
+			Source: "repouri///@/branch",
+			Good:   false,
+		},
+		{
+			Source:    "repouri///#v1.2.3",
+			Stringify: "repouri///#v1.2.3",
+			Good:      true,
+			Uri:       RepositoryUri{Uri: "repouri", Tag: "v1.2.3"},
+		},
+		{
+			Source:    "repouri///#^1.2",
+			Stringify: "repouri///#^1.2",
+			Good:      true,
+			Uri:       RepositoryUri{Uri: "repouri", Tag: "^1.2"},
+		},
+		{
+			Source:    "repouri///#>=1.0,<2.0",
+			Stringify: "repouri///#>=1.0,<2.0",
+			Good:      true,
+			Uri:       RepositoryUri{Uri: "repouri", Tag: ">=1.0,<2.0"},
+		},
+		{
+			Source:    "repouri///:services/api",
+			Stringify: "repouri///:services/api",
+			Good:      true,
+			Uri:       RepositoryUri{Uri: "repouri", Subdir: "services/api"},
+		},
+		{
+			Source:    "repouri///@release/1.2:services/api",
+			Stringify: "repouri///@release/1.2:services/api",
+			Good:      true,
+			Uri:       RepositoryUri{Uri: "repouri", Branch: "release/1.2", Subdir: "services/api"},
+		},
+		{
+			Source:    "repouri///#~1.2:sub",
+			Stringify: "repouri///#~1.2:sub",
+			Good:      true,
+			Uri:       RepositoryUri{Uri: "repouri", Tag: "~1.2", Subdir: "sub"},
+		},
+		{
+			Source: "repouri///:../sub",
+			Good:   false,
+		},
+		{
+			Source: "repouri///@branch@branch",
+			Good:   false,
+		},
 	}
 
 	targetUriCases = []struct {
@@ -89,6 +149,92 @@ var (
 			Good:      true,
 			Uri:       TargetUri{Repository: &RepositoryUri{Uri: "repouri", Commit: "commit"}},
 		},
+		{
+			Source:    "repouri///@release/1.2::target",
+			Stringify: "repouri///@release/1.2::target",
+			Good:      true,
+			Uri:       TargetUri{Repository: &RepositoryUri{Uri: "repouri", Branch: "release/1.2"}, Name: "target"},
+		},
+		{
+			Source:    "repouri///#^1.2:sub::target",
+			Stringify: "repouri///#^1.2:sub::target",
+			Good:      true,
+			Uri:       TargetUri{Repository: &RepositoryUri{Uri: "repouri", Tag: "^1.2", Subdir: "sub"}, Name: "target"},
+		},
+		{
+			Source:    "repouri///:sub:://services/...",
+			Stringify: "repouri///:sub:://services/...",
+			Good:      true,
+			Uri:       TargetUri{Repository: &RepositoryUri{Uri: "repouri", Subdir: "sub"}, Path: "services/..."},
+		},
+		{
+			Source:    "repouri::*",
+			Stringify: "repouri::*",
+			Good:      true,
+			Uri:       TargetUri{Repository: &RepositoryUri{Uri: "repouri"}, Name: "*"},
+		},
+		{
+			Source:    "repouri///@branch::svc-*",
+			Stringify: "repouri///@branch::svc-*",
+			Good:      true,
+			Uri:       TargetUri{Repository: &RepositoryUri{Uri: "repouri", Branch: "branch"}, Name: "svc-*"},
+		},
+		{
+			Source:    "//services/...",
+			Stringify: "//services/...",
+			Good:      true,
+			Uri:       TargetUri{Path: "services/..."},
+		},
+		{
+			Source:    "//...",
+			Stringify: "//...",
+			Good:      true,
+			Uri:       TargetUri{Path: "..."},
+		},
+		{
+			Source:    "repouri:://services/api",
+			Stringify: "repouri:://services/api",
+			Good:      true,
+			Uri:       TargetUri{Repository: &RepositoryUri{Uri: "repouri"}, Path: "services/api"},
+		},
+		{
+			Source: "repouri:://services/../api",
+			Good:   false,
+		},
+	}
+
+	versionRangeCases = []struct {
+		Range    string
+		Tags     []string
+		Resolved string
+		Good     bool
+	}{
+		{Range: "^1.2", Tags: []string{"v1.1.0", "v1.2.0", "v1.9.3", "v2.0.0"}, Resolved: "v1.9.3", Good: true},
+		{Range: "^0.2.1", Tags: []string{"0.2.0", "0.2.5", "0.3.0"}, Resolved: "0.2.5", Good: true},
+		{Range: "~1.2", Tags: []string{"1.2.0", "1.2.7", "1.3.0"}, Resolved: "1.2.7", Good: true},
+		{Range: "1.x", Tags: []string{"1.0.0", "1.4.0", "2.0.0", "latest"}, Resolved: "1.4.0", Good: true},
+		{Range: ">=1.0,<2.0", Tags: []string{"v0.9.0", "v1.5.0", "v2.0.0-rc1", "v2.0.0"}, Resolved: "v1.5.0", Good: true},
+		{Range: ">=3.0", Tags: []string{"v1.0.0", "v2.0.0"}, Good: false},
+		{Range: "^a.b", Tags: []string{"v1.0.0"}, Good: false},
+	}
+
+	targetPatternCases = []struct {
+		Source string
+		Name   string
+		Path   string
+		Match  bool
+	}{
+		{Source: "target", Name: "target", Path: "", Match: true},
+		{Source: "target", Name: "target2", Path: "", Match: false},
+		{Source: "repouri::*", Name: "target", Path: "services/api", Match: true},
+		{Source: "svc-*", Name: "svc-api", Path: "", Match: true},
+		{Source: "svc-*", Name: "web", Path: "", Match: false},
+		{Source: "//...", Name: "target", Path: "", Match: true},
+		{Source: "//services/...", Name: "api", Path: "services/api", Match: true},
+		{Source: "//services/...", Name: "api", Path: "services", Match: true},
+		{Source: "//services/...", Name: "api", Path: "services2/api", Match: false},
+		{Source: "//services/api", Name: "api", Path: "./services/api/", Match: true},
+		{Source: "//services/api", Name: "api", Path: "services/api/v2", Match: false},
 	}
 )
 
@@ -145,3 +291,37 @@ func TestTargetUri(t *testing.T) {
 		}
 	}
 }
+
+func TestTargetUriMatch(t *testing.T) {
+	for _, tCase := range targetPatternCases {
+		r := ParseTargetUri(tCase.Source)
+		if r == nil {
+			t.Errorf("Failed to parse [%s]", tCase.Source)
+			continue
+		}
+		if r.Match(tCase.Name, tCase.Path) != tCase.Match {
+			t.Errorf("Incorrect match result of [%s] with name [%s] path [%s]. Expect [%v]", tCase.Source, tCase.Name, tCase.Path, tCase.Match)
+		}
+	}
+}
+
+func TestResolveVersionRange(t *testing.T) {
+	for _, tCase := range versionRangeCases {
+		if !IsVersionRange(tCase.Range) {
+			t.Errorf("[%s] should be a version range", tCase.Range)
+			continue
+		}
+		resolved, err := ResolveVersionRange(tCase.Range, tCase.Tags)
+		if tCase.Good {
+			if err != nil {
+				t.Errorf("Failed to resolve [%s], error: %s", tCase.Range, err)
+				continue
+			}
+			if resolved != tCase.Resolved {
+				t.Errorf("Incorrect resolved tag of [%s]. Expect [%s] Actual [%s]", tCase.Range, tCase.Resolved, resolved)
+			}
+		} else if err == nil {
+			t.Errorf("Range [%s] should not be resolved, resolved [%s]", tCase.Range, resolved)
+		}
+	}
+}
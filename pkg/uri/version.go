@@ -0,0 +1,241 @@
+// Author: lipixun
+// Created Time : 三 12/28 10:42:17 2016
+//
+// File Name: version.go
+// Description:
+//	The semver and semver range used by the tag of repository uri
+//	The range is a comma separated list of comparators, all comparators must be matched:
+//		^1.2.3		>=1.2.3,<2.0.0 (the left-most non-zero part is kept)
+//		~1.2.3		>=1.2.3,<1.3.0
+//		1.2.x		>=1.2.0,<1.3.0 (x, X and * are wildcards)
+//		>=1.0,<2	Comparators with operator >, >=, <, <= and =
+package uri
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+}
+
+// Parse version like v1.2.3, 1.2.3-rc1 or 1.2 (the missing parts are 0)
+func ParseVersion(s string) (*Version, error) {
+	version, _, err := parsePartialVersion(s, false)
+	if err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// Parse version, returns the number of the specified parts (which are not wildcards)
+func parsePartialVersion(s string, allowWildcard bool) (*Version, int, error) {
+	raw := s
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "v"), "V")
+	// Drop the build metadata
+	if idx := strings.Index(s, "+"); idx != -1 {
+		s = s[:idx]
+	}
+	var version Version
+	if idx := strings.Index(s, "-"); idx != -1 {
+		version.Prerelease = s[idx+1:]
+		s = s[:idx]
+	}
+	parts := strings.Split(s, ".")
+	if s == "" || len(parts) > 3 {
+		return nil, 0, errors.New(fmt.Sprintf("Invalid version [%s]", raw))
+	}
+	numbers := []*int{&version.Major, &version.Minor, &version.Patch}
+	count := 0
+	for i, part := range parts {
+		if part == "x" || part == "X" || part == "*" {
+			if !allowWildcard {
+				return nil, 0, errors.New(fmt.Sprintf("Invalid version [%s]", raw))
+			}
+			break
+		}
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return nil, 0, errors.New(fmt.Sprintf("Invalid version [%s]", raw))
+		}
+		*numbers[i] = number
+		count++
+	}
+	// Done
+	return &version, count, nil
+}
+
+// Compare with another version, returns -1, 0 or 1
+func (this *Version) Compare(v *Version) int {
+	for _, pair := range [][2]int{{this.Major, v.Major}, {this.Minor, v.Minor}, {this.Patch, v.Patch}} {
+		if pair[0] < pair[1] {
+			return -1
+		} else if pair[0] > pair[1] {
+			return 1
+		}
+	}
+	// The version without prerelease is greater
+	if this.Prerelease == v.Prerelease {
+		return 0
+	} else if this.Prerelease == "" {
+		return 1
+	} else if v.Prerelease == "" {
+		return -1
+	} else if this.Prerelease < v.Prerelease {
+		return -1
+	} else {
+		return 1
+	}
+}
+
+func (this *Version) String() string {
+	if this.Prerelease != "" {
+		return fmt.Sprintf("%d.%d.%d-%s", this.Major, this.Minor, this.Patch, this.Prerelease)
+	}
+	return fmt.Sprintf("%d.%d.%d", this.Major, this.Minor, this.Patch)
+}
+
+type versionComparator struct {
+	Operator string
+	Version  Version
+}
+
+func (this *versionComparator) Match(v *Version) bool {
+	result := v.Compare(&this.Version)
+	switch this.Operator {
+	case ">":
+		return result > 0
+	case ">=":
+		return result >= 0
+	case "<":
+		return result < 0
+	case "<=":
+		return result <= 0
+	default:
+		return result == 0
+	}
+}
+
+type VersionRange struct {
+	comparators []*versionComparator
+}
+
+// Check if the string is a semver range rather than an exact version or tag
+func IsVersionRange(s string) bool {
+	if s == "" {
+		return false
+	}
+	if strings.ContainsAny(s, "^~<>=*,") {
+		return true
+	}
+	for _, part := range strings.Split(s, ".") {
+		if part == "x" || part == "X" {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse the semver range
+func ParseVersionRange(s string) (*VersionRange, error) {
+	var versionRange VersionRange
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			return nil, errors.New(fmt.Sprintf("Invalid version range [%s]", s))
+		}
+		if item == "*" || item == "x" || item == "X" {
+			// Match any version
+			continue
+		}
+		// Get the operator
+		var operator string
+		for _, op := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+			if strings.HasPrefix(item, op) {
+				operator = op
+				item = item[len(op):]
+				break
+			}
+		}
+		version, count, err := parsePartialVersion(item, operator == "" || operator == "=")
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid version range [%s], error: %s", s, err))
+		}
+		switch operator {
+		case ">", ">=", "<", "<=":
+			versionRange.comparators = append(versionRange.comparators, &versionComparator{operator, *version})
+		case "^":
+			// Keep the left-most non-zero part
+			var upper Version
+			if version.Major > 0 || count == 1 {
+				upper = Version{Major: version.Major + 1}
+			} else if version.Minor > 0 || count == 2 {
+				upper = Version{Minor: version.Minor + 1}
+			} else {
+				upper = Version{Patch: version.Patch + 1}
+			}
+			versionRange.comparators = append(versionRange.comparators, &versionComparator{">=", *version}, &versionComparator{"<", upper})
+		case "~":
+			upper := Version{Major: version.Major, Minor: version.Minor + 1}
+			if count == 1 {
+				upper = Version{Major: version.Major + 1}
+			}
+			versionRange.comparators = append(versionRange.comparators, &versionComparator{">=", *version}, &versionComparator{"<", upper})
+		default:
+			if count == 3 {
+				versionRange.comparators = append(versionRange.comparators, &versionComparator{"=", *version})
+				continue
+			}
+			// Partial version, match all versions with the same prefix
+			upper := Version{Major: version.Major + 1}
+			if count == 2 {
+				upper = Version{Major: version.Major, Minor: version.Minor + 1}
+			} else if count == 0 {
+				return nil, errors.New(fmt.Sprintf("Invalid version range [%s]", s))
+			}
+			versionRange.comparators = append(versionRange.comparators, &versionComparator{">=", *version}, &versionComparator{"<", upper})
+		}
+	}
+	// Done
+	return &versionRange, nil
+}
+
+// Check if the version is in the range
+func (this *VersionRange) Match(v *Version) bool {
+	for _, comparator := range this.comparators {
+		if !comparator.Match(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Resolve the semver range to the greatest matched tag, the tags which are not versions or are prerelease versions are ignored
+func ResolveVersionRange(s string, tags []string) (string, error) {
+	versionRange, err := ParseVersionRange(s)
+	if err != nil {
+		return "", err
+	}
+	var resolvedTag string
+	var resolvedVersion *Version
+	for _, tag := range tags {
+		version, err := ParseVersion(tag)
+		if err != nil || version.Prerelease != "" {
+			continue
+		}
+		if versionRange.Match(version) && (resolvedVersion == nil || version.Compare(resolvedVersion) > 0) {
+			resolvedTag, resolvedVersion = tag, version
+		}
+	}
+	if resolvedVersion == nil {
+		return "", errors.New(fmt.Sprintf("No tag matches version range [%s]", s))
+	}
+	// Done
+	return resolvedTag, nil
+}
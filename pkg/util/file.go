@@ -0,0 +1,38 @@
+// Author: lipixun
+// Created Time : 二 12/27 14:05:18 2016
+//
+// File Name: file.go
+// Description:
+//	The file helper
+package util
+
+import (
+	"io"
+	"os"
+)
+
+// Copy file, the file mode will be preserved
+// Parameters:
+//  src         The source file path
+//  dst         The destination file path, will be truncated if existed
+func CopyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+	dstFile, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dstFile, srcFile); err != nil {
+		dstFile.Close()
+		return err
+	}
+	// Done
+	return dstFile.Close()
+}
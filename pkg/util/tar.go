@@ -8,8 +8,12 @@ package util
 
 import (
 	"archive/tar"
+	"errors"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 // Write file to tar
@@ -62,3 +66,136 @@ func TarWriteData(data []byte, name string, writer *tar.Writer) error {
 	_, err := writer.Write(data)
 	return err
 }
+
+// Write directory to tar recursively, the symbol links are written as links rather than followed
+// Parameters:
+//  path        The source directory path
+//  name        The name of the directory in tar, empty means the root of tar
+//  writer      The tar writer
+// Returns:
+//  The relative path (to the source directory) of the written files and links
+func TarWriteDir(path string, name string, writer *tar.Writer) ([]string, error) {
+	var files []string
+	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		if relPath == "." {
+			return nil
+		}
+		// Write header
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(filepath.Join(name, relPath))
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := writer.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		files = append(files, relPath)
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		// Write data
+		file, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(writer, file)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Done
+	return files, nil
+}
+
+// Extract the tar to the directory, the entries (and the targets of symbol links) outside of the directory are refused
+// Parameters:
+//  reader      The tar reader
+//  dest        The destination directory
+func TarExtract(reader *tar.Reader, dest string) error {
+	for {
+		hdr, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		p, err := getExtractPath(dest, hdr.Name)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(p, os.ModePerm); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if filepath.IsAbs(hdr.Linkname) {
+				return errors.New(fmt.Sprintf("Invalid symbol link [%s] to absolute path [%s]", hdr.Name, hdr.Linkname))
+			}
+			if _, err := getExtractPath(dest, filepath.Join(filepath.Dir(filepath.FromSlash(hdr.Name)), filepath.FromSlash(hdr.Linkname))); err != nil {
+				return errors.New(fmt.Sprintf("Invalid symbol link [%s] to [%s] outside of the extracting directory", hdr.Name, hdr.Linkname))
+			}
+			if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+				return err
+			}
+			if err := os.Symlink(hdr.Linkname, p); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := extractFile(reader, p, os.FileMode(hdr.Mode)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Get the path of the entry to extract, returns error if the entry is outside of the directory
+// The extracted symbol links are never followed, the entry under (or at) an existing symbol link is refused
+func getExtractPath(dest, name string) (string, error) {
+	dest = filepath.Clean(dest)
+	p := filepath.Join(dest, filepath.FromSlash(name))
+	if p != dest && !strings.HasPrefix(p, dest+string(filepath.Separator)) {
+		return "", errors.New(fmt.Sprintf("Invalid entry [%s] outside of the extracting directory", name))
+	}
+	for current := p; current != dest; current = filepath.Dir(current) {
+		if info, err := os.Lstat(current); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return "", errors.New(fmt.Sprintf("Invalid entry [%s] through symbol link [%s]", name, current))
+		}
+	}
+	return p, nil
+}
+
+// Write the data of reader to the file
+func extractFile(reader io.Reader, p string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(p, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(file, reader)
+	return err
+}
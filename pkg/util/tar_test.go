@@ -0,0 +1,74 @@
+// Author: lipixun
+// Created Time : 四 12/29 16:40:12 2016
+//
+// File Name: tar_test.go
+// Description:
+//
+package util
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testTarEntry struct {
+	Name     string
+	Linkname string // Symbol link if not empty
+	Data     string
+}
+
+func newTestTar(t *testing.T, entries []testTarEntry) *tar.Reader {
+	var buffer bytes.Buffer
+	writer := tar.NewWriter(&buffer)
+	for _, entry := range entries {
+		hdr := &tar.Header{Name: entry.Name, Mode: 0644, Size: int64(len(entry.Data)), Typeflag: tar.TypeReg}
+		if entry.Linkname != "" {
+			hdr = &tar.Header{Name: entry.Name, Mode: 0777, Linkname: entry.Linkname, Typeflag: tar.TypeSymlink}
+		}
+		if err := writer.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := writer.Write([]byte(entry.Data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return tar.NewReader(&buffer)
+}
+
+func TestTarExtract(t *testing.T) {
+	for _, c := range []struct {
+		Entries []testTarEntry
+		Valid   bool
+	}{
+		{[]testTarEntry{{Name: "a/b.txt", Data: "b"}, {Name: "a/link", Linkname: "b.txt"}, {Name: "c", Linkname: "a/b.txt"}}, true},
+		{[]testTarEntry{{Name: "../escape.txt", Data: "x"}}, false},
+		{[]testTarEntry{{Name: "link", Linkname: "/tmp"}}, false},
+		{[]testTarEntry{{Name: "a/link", Linkname: "../../escape"}}, false},
+		{[]testTarEntry{{Name: "dir/x.txt", Data: "x"}, {Name: "link", Linkname: "dir"}, {Name: "link/y.txt", Data: "y"}}, false},
+		{[]testTarEntry{{Name: "x.txt", Data: "x"}, {Name: "link", Linkname: "x.txt"}, {Name: "link", Data: "y"}}, false},
+	} {
+		dest, err := ioutil.TempDir("", "tarextract")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = TarExtract(newTestTar(t, c.Entries), filepath.Join(dest, "root"))
+		if c.Valid && err != nil {
+			t.Errorf("Failed to extract %v, error: %s", c.Entries, err)
+		} else if !c.Valid && err == nil {
+			t.Errorf("Expect error when extracting %v", c.Entries)
+		}
+		if c.Valid {
+			if data, err := ioutil.ReadFile(filepath.Join(dest, "root", "c")); err != nil || string(data) != "b" {
+				t.Errorf("Mismatch extracted file. Expected [b] Actually [%s] error: %v", data, err)
+			}
+		}
+		os.RemoveAll(dest)
+	}
+}
@@ -0,0 +1,80 @@
+// Author: lipixun
+// Created Time : 一 12/26 10:12:41 2016
+//
+// File Name: zip.go
+// Description:
+//	The zip utility
+package util
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+)
+
+// Write file to zip
+// Parameters:
+//  path        The source file path
+//  name        The name of the file in zip
+//  writer      The zip writer
+func ZipWriteFile(path string, name string, writer *zip.Writer) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	// Write header
+	hdr, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	hdr.Method = zip.Deflate
+	w, err := writer.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	// Write data
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(w, file)
+	// Done
+	return err
+}
+
+// Extract the zip file to the directory, the entries outside of the directory are refused
+// Parameters:
+//  path        The zip file path
+//  dest        The destination directory
+func ZipExtract(path string, dest string) error {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	for _, file := range reader.File {
+		p, err := getExtractPath(dest, file.Name)
+		if err != nil {
+			return err
+		}
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(p, os.ModePerm); err != nil {
+				return err
+			}
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			return err
+		}
+		err = extractFile(r, p, file.Mode())
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	// Done
+	return nil
+}
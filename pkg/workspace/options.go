@@ -18,6 +18,7 @@ type WorkspaceOptions struct {
 	Verbose      bool                // Show the verbose
 	EnableColor  bool                // Enable the color of the log
 	ThirdService ThirdServiceOptions // The third party options
+	Finder       FinderOptions       // The repository finder options
 }
 
 func NewWorkspaceOptions() *WorkspaceOptions {
@@ -36,6 +37,10 @@ type WorkDirOptions struct {
 	CurrentPathAsProjectPath bool
 }
 
+type FinderOptions struct {
+	SearchPaths []string // The search roots (glob pattern is supported) used by paths and remote finders
+}
+
 type ThirdServiceOptions struct {
 	Docker DockerServiceOptions
 }